@@ -0,0 +1,125 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// archiveWriter bundles generated posts into a single .zip or .tar.gz file
+// instead of writing them to a directory.
+type archiveWriter struct {
+	f   io.WriteCloser
+	zw  *zip.Writer
+	gz  *gzip.Writer
+	tw  *tar.Writer
+	now time.Time
+	// root is the directory the archive stands for; files are stored under
+	// their path relative to it.
+	root string
+}
+
+// newArchiveWriter creates the named archive, inferring the format from the
+// file extension (.zip, .tar.gz or .tgz). Files added with AddFile are
+// stored relative to root.
+func newArchiveWriter(name, root string) (*archiveWriter, error) {
+	lower := strings.ToLower(name)
+	zipped := strings.HasSuffix(lower, ".zip")
+	tarred := strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz")
+	if !zipped && !tarred {
+		return nil, fmt.Errorf("unsupported archive format %q: use .zip, .tar.gz or .tgz", name)
+	}
+
+	f, err := destination.Create(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if root == "" {
+		root = "."
+	}
+	a := &archiveWriter{f: f, now: clock(), root: root}
+	if zipped {
+		a.zw = zip.NewWriter(f)
+	} else {
+		a.gz = gzip.NewWriter(f)
+		a.tw = tar.NewWriter(a.gz)
+	}
+	return a, nil
+}
+
+// AddFile stores data in the archive as the file at filename, which must be
+// inside the archive's root.
+func (a *archiveWriter) AddFile(filename string, data []byte) error {
+	name, err := filepath.Rel(a.root, filename)
+	if err != nil {
+		return fmt.Errorf("%s is outside the archive root %s", filename, a.root)
+	}
+	return a.Add(filepath.ToSlash(name), data)
+}
+
+// Add stores data in the archive under the slash separated path name. Names
+// that are absolute or climb out of the archive with .. are rejected, as
+// extracting them would write outside the target directory.
+func (a *archiveWriter) Add(name string, data []byte) error {
+	name = strings.Replace(name, "\\", "/", -1)
+	if path.IsAbs(name) || (len(name) > 1 && name[1] == ':') {
+		return fmt.Errorf("invalid archive member %q: absolute path", name)
+	}
+	for _, elem := range strings.Split(name, "/") {
+		if elem == ".." {
+			return fmt.Errorf("invalid archive member %q: outside the archive", name)
+		}
+	}
+	name = path.Clean(name)
+
+	if a.zw != nil {
+		w, err := a.zw.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: a.now,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	err := a.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: a.now,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = a.tw.Write(data)
+	return err
+}
+
+// Close flushes the archive and closes the underlying file.
+func (a *archiveWriter) Close() error {
+	var closers []io.Closer
+	if a.zw != nil {
+		closers = append(closers, a.zw)
+	} else {
+		closers = append(closers, a.tw, a.gz)
+	}
+	closers = append(closers, a.f)
+
+	var first error
+	for _, c := range closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
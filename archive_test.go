@@ -0,0 +1,88 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestArchiveMembers(t *testing.T) {
+	feed, err := os.ReadFile(filepath.Join("testdata", "releases.atom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp := t.TempDir()
+	posts := []string{"v1.1.0-quoted--release.md", "v1.2.0-rc.1.md", "v1.2.0.md"}
+
+	tests := []struct {
+		name string
+		dir  string
+		args []string
+		want []string
+	}{
+		{"no target", "", nil, posts},
+		{"relative target", "site", nil, posts},
+		{"absolute target", filepath.Join(tmp, "site"), nil, posts},
+		{"output inside target", filepath.Join(tmp, "site"), []string{"-output", "jekyll:" + filepath.Join(tmp, "site", "_posts")}, append(append([]string(nil), posts...),
+			"_posts/2023-03-01-v1.1.0-quoted--release.md", "_posts/2023-03-20-v1.2.0-rc.1.md", "_posts/2023-04-02-v1.2.0.md")},
+		{"output outside target", filepath.Join(tmp, "site"), []string{"-output", "jekyll:" + filepath.Join(tmp, "other")}, nil},
+		{"output above the current directory", "", []string{"-output", "jekyll:../other"}, nil},
+		{"absolute output", "", []string{"-output", "jekyll:" + filepath.Join(tmp, "other")}, nil},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := filepath.Join(tmp, filepath.Base(t.Name())+".zip")
+			o := &options{}
+			if err := parseArgs(o, append([]string{"-quiet", "-archive", archive}, tt.args...)...); err != nil {
+				t.Fatal(err)
+			}
+			err := run(o, feed, tt.dir)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("test %d: adding files outside the archive root did not fail", i)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			zr, err := zip.OpenReader(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer zr.Close()
+			var got []string
+			for _, f := range zr.File {
+				got = append(got, f.Name)
+			}
+			sort.Strings(got)
+			want := append([]string(nil), tt.want...)
+			sort.Strings(want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("members %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestArchiveAddRejects(t *testing.T) {
+	a, err := newArchiveWriter(filepath.Join(t.TempDir(), "a.zip"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	for _, name := range []string{"/etc/passwd", "../x.md", "a/../../x.md", `..\x.md`, `C:\x.md`} {
+		if err := a.Add(name, nil); err == nil {
+			t.Errorf("Add(%q) did not fail", name)
+		}
+	}
+	for _, name := range []string{"x.md", "a/b/x.md", "a/./x.md"} {
+		if err := a.Add(name, nil); err != nil {
+			t.Errorf("Add(%q): %v", name, err)
+		}
+	}
+}
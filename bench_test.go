@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// benchEntries is the size of the feed the benchmarks work on, about what
+// a busy repo accumulates over the years.
+const benchEntries = 1000
+
+// benchContent is the escaped html of a typical release with generated
+// notes, a checklist, an image and assets.
+const benchContent = `&lt;h2&gt;Upgrade&lt;/h2&gt;
+&lt;ul class="contains-task-list"&gt;
+&lt;li class="task-list-item"&gt;&lt;input type="checkbox" class="task-list-item-checkbox" checked="" disabled=""&gt; Back up the database&lt;/li&gt;
+&lt;li class="task-list-item"&gt;&lt;input type="checkbox" class="task-list-item-checkbox" disabled=""&gt; Run &lt;code&gt;migrate&lt;/code&gt;&lt;/li&gt;
+&lt;/ul&gt;
+&lt;h2&gt;What's Changed&lt;/h2&gt;
+&lt;ul&gt;
+&lt;li&gt;feat: add zones by &lt;a class="user-mention" href="https://github.com/alice"&gt;@alice&lt;/a&gt; in &lt;a href="https://github.com/owner/repo/pull/12"&gt;#12&lt;/a&gt;&lt;/li&gt;
+&lt;li&gt;fix(api): retry on 429 by &lt;a class="user-mention" href="https://github.com/bob"&gt;@bob&lt;/a&gt; in &lt;a href="https://github.com/owner/repo/pull/14"&gt;#14&lt;/a&gt;&lt;/li&gt;
+&lt;li&gt;Update the &lt;strong&gt;README&lt;/strong&gt; and the &lt;em&gt;examples&lt;/em&gt;&lt;/li&gt;
+&lt;/ul&gt;
+&lt;p&gt;&lt;img src="https://example.com/zones.png" alt="Zones"&gt;&lt;/p&gt;
+&lt;pre&gt;&lt;code&gt;go install github.com/owner/repo@latest
+&lt;/code&gt;&lt;/pre&gt;
+&lt;p&gt;&lt;strong&gt;Full Changelog&lt;/strong&gt;: &lt;a href="https://github.com/owner/repo/compare/v1.1.0...v1.2.0"&gt;https://github.com/owner/repo/compare/v1.1.0...v1.2.0&lt;/a&gt;&lt;/p&gt;`
+
+// largeFeed returns an Atom feed of n releases, newest first.
+func largeFeed(n int) []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <id>tag:github.com,2008:https://github.com/owner/repo/releases</id>
+  <title>Release notes from repo</title>
+  <updated>2023-04-02T01:30:00Z</updated>
+`)
+	for i := n; i > 0; i-- {
+		version := fmt.Sprintf("v%d.%d.%d", i/100, i/10%10, i%10)
+		fmt.Fprintf(&b, `  <entry>
+    <id>tag:github.com,2008:Repository/1/%[1]s</id>
+    <updated>2023-04-02T01:30:00Z</updated>
+    <link rel="alternate" type="text/html" href="https://github.com/owner/repo/releases/tag/%[1]s"/>
+    <link rel="enclosure" type="application/gzip" href="https://github.com/owner/repo/releases/download/%[1]s/repo_linux_amd64.tar.gz"/>
+    <title>%[1]s</title>
+    <content type="html">%[2]s</content>
+    <author><name>alice</name><uri>https://github.com/alice</uri></author>
+  </entry>
+`, version, benchContent)
+	}
+	b.WriteString("</feed>\n")
+	return b.Bytes()
+}
+
+func BenchmarkParse(b *testing.B) {
+	feed := largeFeed(benchEntries)
+	b.SetBytes(int64(len(feed)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := parseFeed(feed); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkConvert(b *testing.B) {
+	exp, err := parseFeed(largeFeed(benchEntries))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		convertContents(exp.Entries, nil)
+	}
+}
+
+// BenchmarkWrite runs the whole convert command without converting the
+// bodies, so that rendering and writing the posts make up most of the time.
+func BenchmarkWrite(b *testing.B) {
+	feed := largeFeed(benchEntries)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		convert(b, feed)
+	}
+}
+
+// BenchmarkWriteConverted runs the convert command with -convert and
+// -words, which both need the markdown of each body.
+func BenchmarkWriteConverted(b *testing.B) {
+	feed := largeFeed(benchEntries)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		convert(b, feed, "-convert", "-words")
+	}
+}
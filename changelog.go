@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const changelogPreamble = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/).
+
+`
+
+// changelogSection is a "## [version] - date" section of a CHANGELOG.md.
+type changelogSection struct {
+	version string
+	date    string
+	text    string
+}
+
+var changelogHeaderRe = regexp.MustCompile(`^## (?:\[([^\]]+)\]|(\S+))(?:\s+-\s+(\d{4}-\d{2}-\d{2}))?`)
+
+// newChangelogSection builds the section for an entry, listing the items of
+// the lists in its content. Entries without lists link to the release.
+func newChangelogSection(e Entry, content string) changelogSection {
+	var b strings.Builder
+	date := yearMonthDate(e.Date)
+	fmt.Fprintf(&b, "## [%s] - %s\n\n", e.Title, date)
+
+	items := listItems(content, e.markdown)
+	if len(items) == 0 {
+		if href := e.Links.Alternate().Href; href != "" {
+			items = []string{fmt.Sprintf("See the [release notes](%s).", href)}
+		}
+	}
+	for _, item := range items {
+		fmt.Fprintf(&b, "- %s\n", item)
+	}
+	b.WriteString("\n")
+
+	return changelogSection{version: e.Title, date: date, text: b.String()}
+}
+
+// markdownItemRe matches a markdown list item, capturing its text.
+var markdownItemRe = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+(.*)$`)
+
+// listItems returns the text of every list item in an html or markdown
+// body, flattened to a single line each.
+func listItems(body string, markdown bool) []string {
+	if markdown {
+		var items []string
+		for _, line := range markdownLines(body) {
+			if m := markdownItemRe.FindStringSubmatch(line); m != nil {
+				if text := strings.Join(strings.Fields(m[1]), " "); text != "" {
+					items = append(items, text)
+				}
+			}
+		}
+		return items
+	}
+
+	nodes, err := parseBody(body)
+	if err != nil {
+		return nil
+	}
+
+	var items []string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "li" {
+			if text := strings.Join(strings.Fields(textContent(n)), " "); text != "" {
+				items = append(items, text)
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+	return items
+}
+
+// markdownLines returns the lines of a markdown body outside of fenced code
+// blocks.
+func markdownLines(body string) []string {
+	var lines []string
+	fenced := false
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+			continue
+		}
+		if !fenced {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// textContent returns the concatenated text below n.
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+		if c.Type == html.ElementNode && (c.Data == "br" || c.Data == "p") {
+			b.WriteString(" ")
+		}
+	}
+	return b.String()
+}
+
+// parseChangelog splits a CHANGELOG.md into the text before the first
+// section and its sections.
+func parseChangelog(s string) (string, []changelogSection) {
+	lines := strings.SplitAfter(s, "\n")
+
+	var preamble strings.Builder
+	var sections []changelogSection
+	for _, line := range lines {
+		if m := changelogHeaderRe.FindStringSubmatch(line); m != nil {
+			sections = append(sections, changelogSection{version: m[1] + m[2], date: m[3]})
+		}
+		if len(sections) == 0 {
+			preamble.WriteString(line)
+			continue
+		}
+		sections[len(sections)-1].text += line
+	}
+	return preamble.String(), sections
+}
+
+// updateChangelog merges sections into the changelog file, creating it if
+// needed. Versions already in the file are left as they are, and new ones
+// are inserted in date order below any Unreleased section. It returns the
+// number of sections added.
+func updateChangelog(filename string, sections []changelogSection) (int, error) {
+	b, err := destination.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+
+	preamble, existing := parseChangelog(string(b))
+	if len(b) == 0 {
+		preamble = changelogPreamble
+	}
+
+	known := make(map[string]bool)
+	for _, s := range existing {
+		known[s.version] = true
+	}
+
+	added := 0
+	for _, s := range sections {
+		if known[s.version] {
+			continue
+		}
+		known[s.version] = true
+
+		at := len(existing)
+		for i, e := range existing {
+			if strings.EqualFold(e.version, "unreleased") {
+				continue
+			}
+			if e.date != "" && e.date < s.date {
+				at = i
+				break
+			}
+		}
+		existing = append(existing[:at], append([]changelogSection{s}, existing[at:]...)...)
+		added++
+	}
+
+	if added == 0 {
+		return 0, nil
+	}
+
+	var out strings.Builder
+	out.WriteString(preamble)
+	for i, s := range existing {
+		text := s.text
+		if i < len(existing)-1 && !strings.HasSuffix(text, "\n\n") {
+			text = strings.TrimRight(text, "\n") + "\n\n"
+		}
+		out.WriteString(text)
+	}
+	_, err = writeFile(filename, []byte(strings.TrimRight(out.String(), "\n")+"\n"), true)
+	return added, err
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var semverRe = regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)`)
+
+// parseSemver extracts the major, minor and patch numbers of the first
+// x.y.z version in s.
+func parseSemver(s string) (major, minor, patch int, ok bool) {
+	m := semverRe.FindStringSubmatch(s)
+	if m == nil {
+		return 0, 0, 0, false
+	}
+	major, _ = strconv.Atoi(m[1])
+	minor, _ = strconv.Atoi(m[2])
+	patch, _ = strconv.Atoi(m[3])
+	return major, minor, patch, true
+}
+
+// collapsePatches merges entries for patch releases of the same minor
+// version published within window of each other. The merged entry takes the
+// title, ID, links and date of the highest patch, and its content holds the
+// content of every member under a heading with its title, highest patch
+// first. Entries keep the position of their group's first member.
+func collapsePatches(entries []Entry, window time.Duration) (collapsed []Entry, merged int) {
+	type group struct {
+		key     [2]int
+		members []Entry
+		patches []int
+	}
+	var groups []*group
+
+	near := func(g *group, d Date) bool {
+		for _, m := range g.members {
+			diff := time.Time(m.Date).Sub(time.Time(d))
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff <= window {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, e := range entries {
+		major, minor, patch, ok := parseSemver(e.Title)
+		if !ok {
+			groups = append(groups, &group{key: [2]int{-1, -1}, members: []Entry{e}})
+			continue
+		}
+
+		key := [2]int{major, minor}
+		var found *group
+		for _, g := range groups {
+			if g.key == key && near(g, e.Date) {
+				found = g
+				break
+			}
+		}
+		if found == nil {
+			found = &group{key: key}
+			groups = append(groups, found)
+		}
+		found.members = append(found.members, e)
+		found.patches = append(found.patches, patch)
+	}
+
+	for _, g := range groups {
+		if len(g.members) == 1 {
+			collapsed = append(collapsed, g.members[0])
+			continue
+		}
+
+		order := make([]int, len(g.members))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return g.patches[order[i]] > g.patches[order[j]]
+		})
+
+		e := g.members[order[0]]
+		var content string
+		for _, i := range order {
+			m := g.members[i]
+			if e.markdown {
+				content += "## " + m.Title + "\n\n" + m.Content + "\n\n"
+			} else {
+				content += "<h2>" + html.EscapeString(m.Title) + "</h2>\n" + m.Content + "\n"
+			}
+		}
+		e.Content = content
+
+		collapsed = append(collapsed, e)
+		merged += len(g.members) - 1
+	}
+	return collapsed, merged
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// collision is a post file that more than one release would be written to.
+type collision struct {
+	path    string
+	entries []Entry
+}
+
+// findCollisions returns the post files of outputs that several entries map
+// to, in the order they are first written.
+func findCollisions(outputs []output, entries []Entry) []collision {
+	var paths []string
+	byPath := make(map[string][]Entry)
+	for _, out := range outputs {
+		for _, e := range entries {
+			if strings.TrimSpace(e.Title) == "" {
+				e.Title = synthesizeTitle(e)
+			}
+			e.Prerelease = e.Prerelease || isPrerelease(e.Title)
+			p := out.forEntry(e).path(e, ".md")
+			if _, ok := byPath[p]; !ok {
+				paths = append(paths, p)
+			}
+			byPath[p] = append(byPath[p], e)
+		}
+	}
+
+	var collisions []collision
+	for _, p := range paths {
+		if len(byPath[p]) > 1 {
+			collisions = append(collisions, collision{path: p, entries: byPath[p]})
+		}
+	}
+	return collisions
+}
+
+// reportCollisions writes each collision to w with the titles, dates and IDs
+// of the releases involved.
+func reportCollisions(w io.Writer, collisions []collision) error {
+	for _, c := range collisions {
+		if _, err := fmt.Fprintf(w, "%s would be written by %d releases:\n", c.path, len(c.entries)); err != nil {
+			return err
+		}
+		for _, e := range c.entries {
+			if _, err := fmt.Fprintf(w, "  %s (%s, %s)\n", e.Title, yearMonthDate(e.Date), e.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// conventionalRe matches the conventional commit prefix of a bullet, such
+// as "feat: " or "fix(api)!: ", capturing its type and scope. Only the
+// conventional types are recognized, so "Windows: fix crash" is left alone.
+var conventionalRe = regexp.MustCompile(`^\s*(?i:(feat|fix|docs|chore|refactor|perf|test|build|ci|style|revert))(?:\(([^)]*)\))?!?:(?:\s+|$)`)
+
+// conventionalGroups are the headings of -group-by-conventional, in order,
+// and the commit types under each. Other types go under the last heading.
+var conventionalGroups = []struct {
+	heading string
+	types   []string
+}{
+	{"Features", []string{"feat"}},
+	{"Fixes", []string{"fix"}},
+	{"Other", nil},
+}
+
+// conventionalGroup returns the index in conventionalGroups of the bullet
+// text, and the text without its prefix. Text without a conventional commit
+// prefix goes under Other as it is, with ok false.
+func conventionalGroup(text string) (group int, rest string, ok bool) {
+	other := len(conventionalGroups) - 1
+	m := conventionalRe.FindStringSubmatchIndex(text)
+	if m == nil {
+		return other, text, false
+	}
+	typ := strings.ToLower(text[m[2]:m[3]])
+	rest = text[m[1]:]
+	if m[4] >= 0 && m[5] > m[4] {
+		rest = text[m[4]:m[5]] + ": " + rest
+	}
+	for i, g := range conventionalGroups {
+		if contains(g.types, typ) {
+			return i, rest, true
+		}
+	}
+	return other, rest, true
+}
+
+// groupConventional regroups the top level bullet lists of an html or
+// markdown body whose items start with conventional commit prefixes under
+// Features, Fixes and Other headings, in place of the first such list.
+// Bullets without a recognized prefix go under Other. Bodies without any
+// conventional bullet are returned unchanged.
+func groupConventional(body string, markdown bool) (string, error) {
+	if markdown {
+		return groupConventionalMarkdown(body), nil
+	}
+
+	nodes, err := parseBody(body)
+	if err != nil {
+		return "", err
+	}
+
+	groups := make([][]*html.Node, len(conventionalGroups))
+	first := -1
+	var kept []*html.Node
+	for _, n := range nodes {
+		if n.Type != html.ElementNode || n.DataAtom != atom.Ul || !hasConventionalItem(n) {
+			kept = append(kept, n)
+			continue
+		}
+		if first < 0 {
+			first = len(kept)
+		}
+		for li := n.FirstChild; li != nil; li = li.NextSibling {
+			if li.Type != html.ElementNode || li.DataAtom != atom.Li {
+				continue
+			}
+			g := len(conventionalGroups) - 1
+			if text := firstText(li); text != nil {
+				g, text.Data, _ = conventionalGroup(text.Data)
+				// Drop the element left empty by a prefix such as
+				// <strong>fix:</strong>.
+				if p := text.Parent; text.Data == "" && p != li && p.FirstChild == text && p.LastChild == text {
+					if next := p.NextSibling; next != nil && next.Type == html.TextNode {
+						next.Data = strings.TrimLeft(next.Data, " ")
+					}
+					p.Parent.RemoveChild(p)
+				}
+			}
+			groups[g] = append(groups[g], li)
+		}
+	}
+	if first < 0 {
+		return body, nil
+	}
+
+	var grouped []*html.Node
+	for i, items := range groups {
+		if len(items) == 0 {
+			continue
+		}
+		h := &html.Node{Type: html.ElementNode, Data: "h3", DataAtom: atom.H3}
+		h.AppendChild(&html.Node{Type: html.TextNode, Data: conventionalGroups[i].heading})
+		ul := &html.Node{Type: html.ElementNode, Data: "ul", DataAtom: atom.Ul}
+		for _, li := range items {
+			li.Parent.RemoveChild(li)
+			ul.AppendChild(li)
+		}
+		grouped = append(grouped, h, ul)
+	}
+	kept = append(kept[:first:first], append(grouped, kept[first:]...)...)
+	return renderNodes(kept)
+}
+
+// hasConventionalItem reports whether any item of the list ul starts with
+// a conventional commit prefix.
+func hasConventionalItem(ul *html.Node) bool {
+	for li := ul.FirstChild; li != nil; li = li.NextSibling {
+		if li.DataAtom != atom.Li {
+			continue
+		}
+		if text := firstText(li); text != nil && conventionalRe.MatchString(text.Data) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstText returns the first non blank text node under n, or nil.
+func firstText(n *html.Node) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode && strings.TrimSpace(c.Data) != "" {
+			return c
+		}
+		if c.Type == html.ElementNode {
+			if text := firstText(c); text != nil {
+				return text
+			}
+		}
+	}
+	return nil
+}
+
+// groupConventionalMarkdown is groupConventional for markdown bodies. Top
+// level bullets start with "- ", "* " or "+ "; the indented lines after a
+// bullet belong to it.
+func groupConventionalMarkdown(body string) string {
+	lines := strings.Split(body, "\n")
+	groups := make([][]string, len(conventionalGroups))
+	var kept []string
+	first := -1
+	fenced := false
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimLeft(lines[i], " ")
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+		}
+		if fenced || !isBullet(lines[i]) {
+			kept = append(kept, lines[i])
+			continue
+		}
+
+		// Collect the items of the list starting at line i.
+		var items [][]string
+		for i < len(lines) && isBullet(lines[i]) {
+			item := []string{lines[i]}
+			for i+1 < len(lines) && strings.HasPrefix(lines[i+1], " ") && strings.TrimSpace(lines[i+1]) != "" {
+				i++
+				item = append(item, lines[i])
+			}
+			items = append(items, item)
+			i++
+			// A blank line between two bullets does not end the list.
+			if i+1 < len(lines) && strings.TrimSpace(lines[i]) == "" && isBullet(lines[i+1]) {
+				i++
+			}
+		}
+		i--
+
+		if !hasConventionalBullet(items) {
+			for _, item := range items {
+				kept = append(kept, item...)
+			}
+			continue
+		}
+		if first < 0 {
+			first = len(kept)
+		}
+		for _, item := range items {
+			g, rest, _ := conventionalGroup(item[0][2:])
+			item[0] = item[0][:2] + rest
+			groups[g] = append(groups[g], item...)
+		}
+	}
+	if first < 0 {
+		return body
+	}
+
+	var grouped []string
+	for i, items := range groups {
+		if len(items) == 0 {
+			continue
+		}
+		grouped = append(grouped, "### "+conventionalGroups[i].heading, "")
+		grouped = append(grouped, items...)
+		grouped = append(grouped, "")
+	}
+	// The list was followed by a blank line already, or ended the body.
+	if first == len(kept) || strings.TrimSpace(kept[first]) == "" {
+		grouped = grouped[:len(grouped)-1]
+	}
+	kept = append(kept[:first:first], append(grouped, kept[first:]...)...)
+	return strings.Join(kept, "\n")
+}
+
+// hasConventionalBullet reports whether any of the markdown list items
+// starts with a conventional commit prefix.
+func hasConventionalBullet(items [][]string) bool {
+	for _, item := range items {
+		if conventionalRe.MatchString(item[0][2:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBullet reports whether a markdown line starts a top level bullet.
+func isBullet(line string) bool {
+	return strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "+ ")
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// fileSystem is where posts, indexes and the other generated files are
+// written, and their existing versions read back from. ReadFile errors for
+// missing files satisfy os.IsNotExist.
+type fileSystem interface {
+	// Exists reports whether name exists.
+	Exists(name string) bool
+	ReadFile(name string) ([]byte, error)
+	// WriteFile creates or replaces name, and any missing parent
+	// directories, with data.
+	WriteFile(name string, data []byte) error
+	Remove(name string) error
+	Stat(name string) (os.FileInfo, error)
+	MkdirAll(name string) error
+	ReadDir(name string) ([]os.FileInfo, error)
+	// Create creates or truncates name for streaming output, such as an
+	// -archive.
+	Create(name string) (io.WriteCloser, error)
+}
+
+// destination is the fileSystem written to. The command line tool uses the
+// disk; tests swap it for one in memory.
+var destination fileSystem = osFS{}
+
+// osFS is the fileSystem of the operating system.
+type osFS struct{}
+
+func (osFS) Exists(name string) bool {
+	_, err := os.Lstat(name)
+	return err == nil
+}
+
+func (osFS) ReadFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(name)
+}
+
+// WriteFile writes data to a temporary file in the same directory and then
+// renames it over name, so that an interrupted run never leaves a partly
+// written file behind.
+func (osFS) WriteFile(name string, data []byte) error {
+	dir := filepath.Dir(name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(name)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+func (osFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (osFS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFS) MkdirAll(name string) error {
+	return os.MkdirAll(name, 0755)
+}
+
+func (osFS) ReadDir(name string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(name)
+}
+
+func (osFS) Create(name string) (io.WriteCloser, error) {
+	return os.Create(name)
+}
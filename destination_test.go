@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memFS is a fileSystem in memory. It keeps the slices passed to WriteFile,
+// as any fileSystem may. Directories only exist once created with MkdirAll.
+type memFS struct {
+	files map[string][]byte
+	dirs  map[string]bool
+}
+
+func newMemFS() memFS {
+	return memFS{files: map[string][]byte{}, dirs: map[string]bool{}}
+}
+
+func (m memFS) Exists(name string) bool {
+	_, err := m.Stat(name)
+	return err == nil
+}
+
+func (m memFS) ReadFile(name string) ([]byte, error) {
+	b, ok := m.files[path.Clean(name)]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return b, nil
+}
+
+func (m memFS) WriteFile(name string, data []byte) error {
+	m.MkdirAll(path.Dir(name))
+	m.files[path.Clean(name)] = data
+	return nil
+}
+
+func (m memFS) Remove(name string) error {
+	if _, ok := m.files[path.Clean(name)]; !ok {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+	delete(m.files, path.Clean(name))
+	return nil
+}
+
+func (m memFS) Stat(name string) (os.FileInfo, error) {
+	name = path.Clean(name)
+	if b, ok := m.files[name]; ok {
+		return memInfo{name: path.Base(name), size: int64(len(b))}, nil
+	}
+	if m.dirs[name] {
+		return memInfo{name: path.Base(name), dir: true}, nil
+	}
+	return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+}
+
+func (m memFS) MkdirAll(name string) error {
+	for name = path.Clean(name); name != "." && name != "/"; name = path.Dir(name) {
+		m.dirs[name] = true
+	}
+	return nil
+}
+
+func (m memFS) ReadDir(name string) ([]os.FileInfo, error) {
+	var infos []os.FileInfo
+	for f, b := range m.files {
+		if path.Dir(f) == path.Clean(name) {
+			infos = append(infos, memInfo{name: path.Base(f), size: int64(len(b))})
+		}
+	}
+	return infos, nil
+}
+
+func (m memFS) Create(name string) (io.WriteCloser, error) {
+	return &memFile{fs: m, name: name}, nil
+}
+
+type memFile struct {
+	bytes.Buffer
+	fs   memFS
+	name string
+}
+
+func (f *memFile) Close() error { return f.fs.WriteFile(f.name, f.Bytes()) }
+
+type memInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return i.size }
+func (i memInfo) Mode() os.FileMode  { return 0644 }
+func (i memInfo) ModTime() time.Time { return time.Time{} }
+func (i memInfo) IsDir() bool        { return i.dir }
+func (i memInfo) Sys() interface{}   { return nil }
+
+func TestMemoryDestination(t *testing.T) {
+	mem := newMemFS()
+	defer func(d fileSystem) { destination = d }(destination)
+	destination = mem
+
+	b, err := os.ReadFile("testdata/releases.atom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := &options{}
+	if err := parseArgs(o, "-quiet", "-index", "-summary-file", "out/llms.txt", "-single-doc", "out/all.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(o, b, "out"); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"out/v1.2.0.md":                 `title: "repo: v1.2.0"`,
+		"out/v1.2.0-rc.1.md":            `title: "repo: v1.2.0-rc.1"`,
+		"out/v1.1.0-quoted--release.md": `title: "repo: v1.1.0 \"Quoted\" \\ release"`,
+		"out/_index.md":                 "[v1.2.0-rc.1](v1.2.0-rc.1/)",
+		"out/llms.txt":                  "v1.2.0-rc.1",
+		"out/all.md":                    "v1.2.0-rc.1",
+	} {
+		if got := string(mem.files[name]); !strings.Contains(got, want) {
+			t.Errorf("%s does not contain %q:\n%s", name, want, got)
+		}
+	}
+	if len(mem.files) != 6 {
+		t.Errorf("wrote %d files, want 6", len(mem.files))
+	}
+
+	o = &options{}
+	if err := parseArgs(o, "-quiet", "-git-commit"); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(o, b, "git"); err == nil {
+		t.Error("-git-commit into memory did not fail")
+	}
+}
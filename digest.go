@@ -0,0 +1,73 @@
+package main
+
+import (
+	"html"
+	"strings"
+	"time"
+)
+
+// digestEntries groups entries, in their current order, into digests of up
+// to n releases each. A digest is titled after the range of dates it covers
+// and holds the content of every member under a heading with its title.
+func digestEntries(entries []Entry, n int) []Entry {
+	var digests []Entry
+	for start := 0; start < len(entries); start += n {
+		end := start + n
+		if end > len(entries) {
+			end = len(entries)
+		}
+		digests = append(digests, digest(entries[start:end]))
+	}
+	return digests
+}
+
+// digest merges members into a single entry dated after its newest member.
+// Its changelog lists the repos of all members.
+func digest(members []Entry) Entry {
+	oldest, newest := members[0], members[0]
+	for _, m := range members[1:] {
+		if time.Time(m.Date).Before(time.Time(oldest.Date)) {
+			oldest = m
+		}
+		if time.Time(m.Date).After(time.Time(newest.Date)) {
+			newest = m
+		}
+	}
+
+	e := newest
+	from, to := yearMonthDate(oldest.Date), yearMonthDate(newest.Date)
+	e.Title = "Releases from " + from + " to " + to
+	if from == to {
+		e.Title = "Releases on " + from
+	}
+	e.ID = "digest:" + members[0].ID + ".." + members[len(members)-1].ID
+	e.Links = nil
+
+	var content strings.Builder
+	for _, m := range members {
+		title := m.Title
+		if strings.TrimSpace(title) == "" {
+			title = synthesizeTitle(m)
+		}
+		if e.markdown {
+			content.WriteString("## " + title + "\n\n" + m.Content + "\n\n")
+		} else {
+			content.WriteString("<h2>" + html.EscapeString(title) + "</h2>\n" + m.Content + "\n")
+		}
+		e.Links = append(e.Links, m.Links.Enclosures()...)
+		e.sources = appendSources(e.sources, m)
+	}
+	e.Content = content.String()
+	return e
+}
+
+// appendSources adds the repos of m to sources, skipping those already
+// listed.
+func appendSources(sources []string, m Entry) []string {
+	for _, r := range m.repos() {
+		if !contains(sources, r) {
+			sources = append(sources, r)
+		}
+	}
+	return sources
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark-emoji/definition"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// githubEmojis are the emoji shortcodes Github renders, such as :rocket:.
+var githubEmojis = definition.Github()
+
+var shortcodeRe = regexp.MustCompile(`:([a-z0-9_+-]+):`)
+
+// emojifyText replaces the known emoji shortcodes in s with their unicode
+// emoji. Unknown shortcodes, and Github's custom emoji that have no unicode
+// form such as :octocat:, are left as they are.
+func emojifyText(s string) string {
+	return shortcodeRe.ReplaceAllStringFunc(s, func(code string) string {
+		e, ok := githubEmojis.Get(code[1 : len(code)-1])
+		if !ok || !e.IsUnicode() {
+			return code
+		}
+		return string(e.Unicode)
+	})
+}
+
+// emojify replaces the emoji shortcodes of an html or markdown body outside
+// of code.
+func emojify(body string, markdown bool) (string, error) {
+	if markdown {
+		return emojifyMarkdown(body), nil
+	}
+
+	nodes, err := parseBody(body)
+	if err != nil {
+		return "", err
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && (n.DataAtom == atom.Code || n.DataAtom == atom.Pre) {
+			return
+		}
+		if n.Type == html.TextNode {
+			n.Data = emojifyText(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+	return renderNodes(nodes)
+}
+
+// emojifyMarkdown replaces the emoji shortcodes of a markdown body outside
+// of fenced code blocks and `code` spans.
+func emojifyMarkdown(body string) string {
+	lines := strings.Split(body, "\n")
+	fenced := false
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+			continue
+		}
+		if fenced {
+			continue
+		}
+		// Even parts are outside of code spans.
+		parts := strings.Split(line, "`")
+		for j := 0; j < len(parts); j += 2 {
+			parts[j] = emojifyText(parts[j])
+		}
+		lines[i] = strings.Join(parts, "`")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+// ErrNoEntries is returned for a feed without any release.
+var ErrNoEntries = errors.New("No releases found!")
+
+// ErrInvalidFeed matches, with errors.Is, the errors of feeds that cannot be
+// decoded.
+var ErrInvalidFeed = errors.New("invalid feed")
+
+// feedError wraps the error decoding a feed, keeping its message.
+type feedError struct {
+	err error
+}
+
+func (e feedError) Error() string        { return e.err.Error() }
+func (e feedError) Unwrap() error        { return e.err }
+func (e feedError) Is(target error) bool { return target == ErrInvalidFeed }
+
+// ErrWrite is returned when a post or another file cannot be written.
+type ErrWrite struct {
+	// Path is the file that could not be written.
+	Path string
+	// what names the file in the message, e.g. post "v1.2.0" to disk.
+	what string
+	Err  error
+}
+
+func (e *ErrWrite) Error() string { return fmt.Sprintf("Failed writing %s:\n%s", e.what, e.Err) }
+func (e *ErrWrite) Unwrap() error { return e.Err }
+
+// exitError ends the program with an exit status, once run has logged the
+// reason, such as the stale posts found by -check.
+type exitError struct {
+	code int
+}
+
+func (e exitError) Error() string { return fmt.Sprintf("exit status %d", e.code) }
+
+// fatal reports an error returned by run and exits.
+func fatal(err error) {
+	var exit exitError
+	if errors.As(err, &exit) {
+		os.Exit(exit.code)
+	}
+	log.Fatal(err)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// extraKeyRe matches a top level frontmatter line of -extra, "key: value".
+var extraKeyRe = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_-]*):(?:\s+(.*))?$`)
+
+// reservedKeys are the frontmatter keys set by the built-in templates, which
+// -extra must not repeat.
+var reservedKeys = map[string]bool{
+	"title": true, "date": true, "description": true, "changelog": true,
+	"series": true, "version": true, "author": true, "assets": true,
+	"contentHTML": true, "contributors": true, "references": true,
+	"expiryDate": true, "words": true, "readingTime": true, "draft": true,
+	"layout": true, "categories": true, "tags": true,
+	"cover": true, "images": true, "image": true, "published": true, "lang": true,
+	"publishDate": true, "compareURL": true, "fullChangelog": true, "canonicalURL": true, "params": true,
+}
+
+// extraSchemaYAML is the embedded schema of the -extra frontmatter keys.
+//
+//go:embed extra_schema.yml
+var extraSchemaYAML []byte
+
+// extraTypes check that a YAML value is of a schema type.
+var extraTypes = map[string]func(v *yaml.Node) bool{
+	"string": func(v *yaml.Node) bool { return v.Kind == yaml.ScalarNode && v.Tag != "!!null" },
+	"bool":   func(v *yaml.Node) bool { return v.Kind == yaml.ScalarNode && v.Tag == "!!bool" },
+	"int":    func(v *yaml.Node) bool { return v.Kind == yaml.ScalarNode && v.Tag == "!!int" },
+	"number": func(v *yaml.Node) bool { return v.Kind == yaml.ScalarNode && (v.Tag == "!!int" || v.Tag == "!!float") },
+	"date":   func(v *yaml.Node) bool { return v.Kind == yaml.ScalarNode && v.Tag == "!!timestamp" },
+	"list":   func(v *yaml.Node) bool { return v.Kind == yaml.SequenceNode },
+	"map":    func(v *yaml.Node) bool { return v.Kind == yaml.MappingNode },
+	"any":    func(v *yaml.Node) bool { return true },
+}
+
+// extraSchema maps the frontmatter keys -extra may set to the type of their
+// values.
+type extraSchema map[string]string
+
+// newExtraSchema builds the schema from the embedded one and the -extra-key
+// additions, each of the form key:type.
+func newExtraSchema(keys []string) (extraSchema, error) {
+	s := make(extraSchema)
+	if err := yaml.Unmarshal(extraSchemaYAML, &s); err != nil {
+		return nil, fmt.Errorf("invalid embedded schema: %w", err)
+	}
+	for _, spec := range keys {
+		i := strings.LastIndex(spec, ":")
+		if i < 0 || !extraKeyRe.MatchString(spec[:i]+":") {
+			return nil, fmt.Errorf("invalid -extra-key %q: expected key:type", spec)
+		}
+		s[spec[:i]] = spec[i+1:]
+	}
+	for key, typ := range s {
+		if extraTypes[typ] == nil {
+			return nil, fmt.Errorf("invalid type %q of key %q: expected one of string, bool, int, number, date, list, map or any", typ, key)
+		}
+	}
+	return s, nil
+}
+
+// yamlLineRe matches the line number yaml.v3 starts its syntax errors with.
+var yamlLineRe = regexp.MustCompile(`^yaml: line (\d+): `)
+
+// parseExtra checks the additional frontmatter given with -extra or
+// -extra-file against the schema. It must be a YAML mapping of keys from the
+// schema, each with a value of the key's type, that releasetoblog does not
+// set itself. name identifies the source in errors, which report the line
+// and key at fault.
+func (s extraSchema) parseExtra(name, text string) (string, error) {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "\t") {
+			return "", fmt.Errorf("%s line %d: indent with spaces, not tabs", name, i+1)
+		}
+	}
+	text = strings.Join(lines, "\n")
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &doc); err != nil {
+		msg := err.Error()
+		if m := yamlLineRe.FindStringSubmatch(msg); m != nil {
+			return "", fmt.Errorf("%s line %s: %s", name, m[1], msg[len(m[0]):])
+		}
+		return "", fmt.Errorf("%s: %s", name, strings.TrimPrefix(msg, "yaml: "))
+	}
+	if len(doc.Content) == 0 {
+		return text, nil
+	}
+	m := doc.Content[0]
+	if m.Kind != yaml.MappingNode {
+		return "", fmt.Errorf("%s line %d: expected \"key: value\" lines", name, m.Line)
+	}
+
+	seen := map[string]int{}
+	for i := 0; i < len(m.Content); i += 2 {
+		k, v := m.Content[i], m.Content[i+1]
+		key, n := k.Value, k.Line
+		if reservedKeys[key] {
+			return "", fmt.Errorf("%s line %d: key %q is already set by releasetoblog", name, n, key)
+		}
+		if prev, ok := seen[key]; ok {
+			return "", fmt.Errorf("%s line %d: key %q repeats line %d", name, n, key, prev)
+		}
+		seen[key] = n
+
+		typ, ok := s[key]
+		if !ok {
+			if like := s.closest(key); like != "" {
+				return "", fmt.Errorf("%s line %d: unknown key %q, did you mean %q?", name, n, key, like)
+			}
+			return "", fmt.Errorf("%s line %d: unknown key %q, declare it with -extra-key %s:type", name, n, key, key)
+		}
+		if !extraTypes[typ](v) {
+			return "", fmt.Errorf("%s line %d: key %q must be of type %s", name, v.Line, key, typ)
+		}
+	}
+	return text, nil
+}
+
+// closest returns the key of the schema that key most likely misspells, or
+// "" if there is none.
+func (s extraSchema) closest(key string) string {
+	best, dist := "", 3
+	for k := range s {
+		d := editDistance(strings.ToLower(key), strings.ToLower(k))
+		if d < dist || d == dist && k < best {
+			best, dist = k, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// loadExtra combines and validates the -extra flag and the -extra-file file.
+// The lines of switches, set by flags such as -set-toc, come first.
+func (s extraSchema) loadExtra(extra, filename string, switches ...string) (string, error) {
+	parts := append([]string(nil), switches...)
+	if extra != "" {
+		e, err := s.parseExtra("-extra", strings.ReplaceAll(extra, `\n`, "\n"))
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, e)
+	}
+	if filename != "" {
+		b, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return "", err
+		}
+		e, err := s.parseExtra(filename, string(b))
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, e)
+	}
+
+	combined := strings.Join(parts, "\n")
+	if _, err := s.parseExtra("extra frontmatter", combined); err != nil {
+		return "", err
+	}
+	return combined, nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseExtra(t *testing.T) {
+	s, err := newExtraSchema([]string{"rating:number", "owner:string"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, text, err string
+	}{
+		{"valid", "weight: 10\ntoc: true\naliases:\n  - /old/\n# note\nlastmod: 2023-04-02\nrating: 4.5\nowner: alice\n", ""},
+		{"empty", "# only a comment\n", ""},
+		{"unknown key", "weight: 10\nfeatured: true\ncolour: red\n", `line 3: unknown key "colour", declare it with -extra-key colour:type`},
+		{"misspelt key", "wieght: 10\n", `line 1: unknown key "wieght", did you mean "weight"?`},
+		{"case", "showTOC: true\n", `line 1: unknown key "showTOC", did you mean "showToc"?`},
+		{"bool", "toc: yes please\n", `line 1: key "toc" must be of type bool`},
+		{"int", "weight: ten\n", `line 1: key "weight" must be of type int`},
+		{"list", "toc: true\naliases: /old/\n", `line 2: key "aliases" must be of type list`},
+		{"nested value line", "sitemap:\n  - priority\n", `line 2: key "sitemap" must be of type map`},
+		{"reserved", "weight: 1\ntitle: x\n", `line 2: key "title" is already set by releasetoblog`},
+		{"repeated", "weight: 1\ntoc: true\nweight: 2\n", `line 3: key "weight" repeats line 1`},
+		{"tab", "menu:\n\tmain: x\n", "line 2: indent with spaces, not tabs"},
+		{"syntax", "weight: 1\ntoc: true\nsummary: a: b\n", "line 3: mapping values are not allowed in this context"},
+		{"not a mapping", "- weight\n", `line 1: expected "key: value" lines`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.parseExtra("extra.yml", tt.text)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || err.Error() != "extra.yml "+tt.err {
+				t.Errorf("got error %v, want %q", err, "extra.yml "+tt.err)
+			}
+		})
+	}
+}
+
+func TestExtraSchema(t *testing.T) {
+	s, err := newExtraSchema(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, typ := range s {
+		if reservedKeys[key] {
+			t.Errorf("the schema has %q, which releasetoblog sets itself", key)
+		}
+		if extraTypes[typ] == nil {
+			t.Errorf("key %q has unknown type %q", key, typ)
+		}
+	}
+	for _, spec := range []string{"rating", "rating:float", "bad key:string"} {
+		if _, err := newExtraSchema([]string{spec}); err == nil {
+			t.Errorf("-extra-key %q did not fail", spec)
+		}
+	}
+}
+
+func TestExtraFile(t *testing.T) {
+	feed, err := os.ReadFile(filepath.Join("testdata", "releases.atom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	extra := filepath.Join(t.TempDir(), "extra.yml")
+	if err := os.WriteFile(extra, []byte("weight: 10\nrating: 5\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	o := &options{}
+	if err := parseArgs(o, "-quiet", "-extra-file", extra); err != nil {
+		t.Fatal(err)
+	}
+	err = run(o, feed, t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), extra+` line 2: unknown key "rating"`) {
+		t.Fatalf("got error %v, want the unknown key", err)
+	}
+
+	post, err := os.ReadFile(filepath.Join(convert(t, feed, "-extra-file", extra, "-extra-key", "rating:int"), "v1.2.0.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(post), "\nweight: 10\nrating: 5\n") {
+		t.Errorf("post does not have the extra frontmatter:\n%s", post)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// frontmatterKey is a top level key of YAML frontmatter with the lines of
+// its value, kept verbatim.
+type frontmatterKey struct {
+	name string
+	text string
+}
+
+// splitFrontmatter splits a post into its YAML frontmatter, without the ---
+// delimiters, and its body.
+func splitFrontmatter(post string) (fm, body string, ok bool) {
+	if !strings.HasPrefix(post, "---\n") {
+		return "", post, false
+	}
+	rest := post[len("---\n"):]
+	if strings.HasPrefix(rest, "---\n") {
+		return "", rest[len("---\n"):], true
+	}
+	i := strings.Index(rest, "\n---\n")
+	if i < 0 {
+		return "", post, false
+	}
+	return rest[:i+1], rest[i+len("\n---\n"):], true
+}
+
+// frontmatterKeys splits frontmatter into its top level keys. Indented and
+// list lines belong to the key above them; comments and blank lines go with
+// the key that follows.
+func frontmatterKeys(fm string) []frontmatterKey {
+	var keys []frontmatterKey
+	var pending string
+	for _, line := range strings.SplitAfter(fm, "\n") {
+		if line == "" {
+			continue
+		}
+		m := extraKeyRe.FindStringSubmatch(strings.TrimRight(line, "\n"))
+		switch {
+		case m != nil:
+			keys = append(keys, frontmatterKey{name: m[1], text: pending + line})
+			pending = ""
+		case len(keys) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "-")):
+			keys[len(keys)-1].text += line
+		default:
+			pending += line
+		}
+	}
+	return keys
+}
+
+// yamlKeys splits frontmatter into its top level keys like frontmatterKeys,
+// but finds the keys by parsing it as YAML, so that multi-line strings and
+// flow values holding "key:" lines are not mistaken for keys.
+func yamlKeys(fm string) ([]frontmatterKey, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(fm), &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	m := doc.Content[0]
+	if m.Kind != yaml.MappingNode {
+		return nil, errors.New("frontmatter is not a mapping")
+	}
+
+	// Each key takes the lines from its own, or from the comments above
+	// it, to the next key.
+	lines := strings.SplitAfter(fm, "\n")
+	var keys []frontmatterKey
+	var starts []int
+	for i := 0; i < len(m.Content); i += 2 {
+		k := m.Content[i]
+		start := k.Line - 1
+		if len(keys) == 0 {
+			start = 0
+		} else if k.HeadComment != "" {
+			start -= strings.Count(k.HeadComment, "\n") + 1
+		}
+		keys = append(keys, frontmatterKey{name: k.Value})
+		starts = append(starts, start)
+	}
+	for i := range keys {
+		end := len(lines)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		keys[i].text = strings.Join(lines[starts[i]:end], "")
+	}
+	return keys, nil
+}
+
+// mergeFrontmatter returns the generated post with the frontmatter keys of
+// the existing post that were added by hand, such as "featured: true",
+// appended to its frontmatter. Keys releasetoblog sets itself are dropped
+// when the generated post lacks them, so that a draft: true or words key
+// from an earlier run with other flags does not linger.
+func mergeFrontmatter(existing, generated string) string {
+	oldFM, _, ok := splitFrontmatter(existing)
+	if !ok {
+		return generated
+	}
+	newFM, body, ok := splitFrontmatter(generated)
+	if !ok {
+		return generated
+	}
+
+	oldKeys, err := yamlKeys(oldFM)
+	if err != nil {
+		// Hand edits can leave the frontmatter invalid; keep what can be
+		// told apart line by line.
+		oldKeys = frontmatterKeys(oldFM)
+	}
+
+	// Generated keys may have been written under their -key-map name or,
+	// before it was set, under their own.
+	set := make(map[string]bool)
+	for name := range reservedKeys {
+		set[name] = true
+		set[mappedKey(name)] = true
+	}
+	for _, k := range frontmatterKeys(newFM) {
+		set[k.name] = true
+	}
+
+	var kept strings.Builder
+	for _, k := range oldKeys {
+		if !set[k.name] {
+			kept.WriteString(k.text)
+		}
+	}
+	return "---\n" + newFM + kept.String() + "---\n" + body
+}
+
+// mergeEntry writes the entry like writeEntry but, when its file exists,
+// keeps the frontmatter keys added to it by hand. It reports whether the
+// file changed.
+func mergeEntry(out output, e Entry) (bool, error) {
+	b, err := renderEntry(out, e)
+	if err != nil {
+		return false, err
+	}
+
+	filename := out.path(e, ".md")
+	old, err := destination.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return writeFile(filename, b, false)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	merged := []byte(mergeFrontmatter(string(old), string(b)))
+	if bytes.Equal(merged, old) {
+		return false, nil
+	}
+	return writeFile(filename, merged, true)
+}
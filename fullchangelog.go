@@ -0,0 +1,69 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// compareLinkRe matches the URL of a Github comparison between two tags.
+var compareLinkRe = regexp.MustCompile(`^https://github\.com/[^/\s]+/[^/\s]+/compare/[^\s<>()]+$`)
+
+// fullChangelogLineRe matches the markdown "Full Changelog" line of Github's
+// generated release notes, capturing its URL.
+var fullChangelogLineRe = regexp.MustCompile(`^\s*(?:\*\*|__)?Full Changelog(?:\*\*|__)?\s*:\s*(?:\[[^\]]*\]\()?<?(\S+?)>?\)?\s*$`)
+
+// isFullChangelog reports whether text, the text of a paragraph, is the
+// "Full Changelog" line.
+func isFullChangelog(text string) bool {
+	return strings.HasPrefix(strings.TrimSpace(text), "Full Changelog")
+}
+
+// fullChangelog finds the "**Full Changelog**: <compare URL>" line that ends
+// Github's generated release notes in an html or markdown body. It returns
+// the URL, or "" when the body has no such line, and the body without the
+// line when strip is set.
+func fullChangelog(body string, markdown, strip bool) (string, string, error) {
+	if markdown {
+		lines := strings.Split(body, "\n")
+		for i, line := range lines {
+			m := fullChangelogLineRe.FindStringSubmatch(line)
+			if m == nil || !compareLinkRe.MatchString(m[1]) {
+				continue
+			}
+			if strip {
+				body = strings.TrimSpace(strings.Join(append(lines[:i:i], lines[i+1:]...), "\n"))
+			}
+			return m[1], body, nil
+		}
+		return "", body, nil
+	}
+
+	nodes, err := parseBody(body)
+	if err != nil {
+		return "", "", err
+	}
+	for i, n := range nodes {
+		if n.Type != html.ElementNode || n.DataAtom != atom.P || !isFullChangelog(textContent(n)) {
+			continue
+		}
+		var link string
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.DataAtom == atom.A && compareLinkRe.MatchString(attr(c, "href")) {
+				link = attr(c, "href")
+				break
+			}
+		}
+		if link == "" {
+			continue
+		}
+		if !strip {
+			return link, body, nil
+		}
+		body, err = renderNodes(append(nodes[:i:i], nodes[i+1:]...))
+		return link, body, err
+	}
+	return "", body, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// This file holds the general purpose functions of funcMap, for custom
+// templates. Names and argument order follow the Sprig library, so that
+// pipelines such as {{ .Title | trimPrefix "v" | upper }} read the same.
+
+// defaultValue returns value, or def when value is empty.
+func defaultValue(def, value interface{}) interface{} {
+	if value == nil {
+		return def
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		if v.Len() == 0 {
+			return def
+		}
+	case reflect.Bool:
+		if !v.Bool() {
+			return def
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() == 0 {
+			return def
+		}
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return def
+		}
+	}
+	if z, ok := value.(interface{ IsZero() bool }); ok && z.IsZero() {
+		return def
+	}
+	return value
+}
+
+// list returns its arguments as a list.
+func list(items ...interface{}) []interface{} {
+	return items
+}
+
+// first returns the first element of a list, or nil if it is empty.
+func first(l interface{}) interface{} {
+	v := reflect.ValueOf(l)
+	if (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Len() == 0 {
+		return nil
+	}
+	return v.Index(0).Interface()
+}
+
+// last returns the last element of a list, or nil if it is empty.
+func last(l interface{}) interface{} {
+	v := reflect.ValueOf(l)
+	if (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Len() == 0 {
+		return nil
+	}
+	return v.Index(v.Len() - 1).Interface()
+}
+
+// join joins the elements of a list, formatted as strings, with sep.
+func join(sep string, l interface{}) string {
+	v := reflect.ValueOf(l)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return fmt.Sprint(l)
+	}
+	parts := make([]string, v.Len())
+	for i := range parts {
+		parts[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return strings.Join(parts, sep)
+}
+
+// formatDate formats a date with a Go time layout.
+func formatDate(layout string, date Date) string {
+	return date.Time().Format(layout)
+}
+
+// dateModify shifts a date by a duration such as "-24h" or "90m".
+func dateModify(duration string, date Date) (Date, error) {
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return date, err
+	}
+	return Date(time.Time(date).Add(d)), nil
+}
+
+// relUnits are the units of reltime, largest first.
+var relUnits = []struct {
+	name string
+	d    time.Duration
+}{
+	{"year", 365 * 24 * time.Hour},
+	{"month", 30 * 24 * time.Hour},
+	{"day", 24 * time.Hour},
+	{"hour", time.Hour},
+	{"minute", time.Minute},
+}
+
+// reltime describes a date relative to the clock, e.g. "3 days ago" or
+// "in 2 hours", in the largest whole unit.
+func reltime(date Date) string {
+	d := clock().Sub(time.Time(date))
+	future := d < 0
+	if future {
+		d = -d
+	}
+	for _, u := range relUnits {
+		n := int(d / u.d)
+		if n < 1 {
+			continue
+		}
+		s := fmt.Sprintf("%d %s", n, u.name)
+		if n > 1 {
+			s += "s"
+		}
+		if future {
+			return "in " + s
+		}
+		return s + " ago"
+	}
+	return "just now"
+}
+
+func splitList(sep, s string) []string     { return strings.Split(s, sep) }
+func trimPrefix(prefix, s string) string   { return strings.TrimPrefix(s, prefix) }
+func trimSuffix(suffix, s string) string   { return strings.TrimSuffix(s, suffix) }
+func replace(old, new, s string) string    { return strings.ReplaceAll(s, old, new) }
+func containsString(substr, s string) bool { return strings.Contains(s, substr) }
+func hasPrefix(prefix, s string) bool      { return strings.HasPrefix(s, prefix) }
+func hasSuffix(suffix, s string) bool      { return strings.HasSuffix(s, suffix) }
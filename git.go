@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// gitCommit stages paths in the git repository containing dir and commits
+// them. It reports false, without committing, when none of the paths
+// changed. author, if set, is passed to git commit --author.
+func gitCommit(dir string, paths []string, message, author string) (bool, error) {
+	if len(paths) == 0 {
+		return false, nil
+	}
+
+	abs := make([]string, len(paths))
+	for i, p := range paths {
+		a, err := filepath.Abs(p)
+		if err != nil {
+			return false, err
+		}
+		abs[i] = a
+	}
+
+	if err := git(dir, append([]string{"add", "-A", "--"}, abs...)...); err != nil {
+		return false, err
+	}
+
+	// diff --quiet exits with 1 when there are staged changes.
+	err := git(dir, append([]string{"diff", "--cached", "--quiet", "--"}, abs...)...)
+	if err == nil {
+		return false, nil
+	}
+	if exit, ok := err.(*gitError); !ok || exit.code != 1 {
+		return false, err
+	}
+
+	args := []string{"commit", "-q", "-m", message}
+	if author != "" {
+		args = append(args, "--author", author)
+	}
+	args = append(args, "--")
+	if err := git(dir, append(args, abs...)...); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+type gitError struct {
+	args   []string
+	code   int
+	stderr string
+}
+
+func (e *gitError) Error() string {
+	return fmt.Sprintf("git %s: exit status %d: %s", e.args[0], e.code, strings.TrimSpace(e.stderr))
+}
+
+func git(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if exit, ok := err.(*exec.ExitError); ok {
+		return &gitError{args: args, code: exit.ExitCode(), stderr: stderr.String()}
+	}
+	return err
+}
+
+// commitMessage summarizes the releases written in a run.
+func commitMessage(repo string, titles []string) string {
+	if len(titles) == 0 {
+		return "Update release posts"
+	}
+	subject := fmt.Sprintf("Update %d release posts", len(titles))
+	if repo != "" {
+		subject = fmt.Sprintf("Update %d %s release posts", len(titles), repo)
+	}
+
+	var b strings.Builder
+	b.WriteString(subject)
+	b.WriteString("\n\n")
+	for _, t := range titles {
+		b.WriteString("- " + t + "\n")
+	}
+	return b.String()
+}
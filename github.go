@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// githubAPI is the base URL of the Github REST API.
+var githubAPI = "https://api.github.com"
+
+// releaseTag splits a release page URL such as
+// https://github.com/owner/repo/releases/tag/v1.2.0 into its repo and tag.
+func releaseTag(link string) (repo, tag string, ok bool) {
+	u, err := url.Parse(link)
+	if err != nil || u.Host != "github.com" {
+		return "", "", false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 5 || parts[2] != "releases" || parts[3] != "tag" {
+		return "", "", false
+	}
+	tag, err = url.PathUnescape(strings.Join(parts[4:], "/"))
+	if err != nil {
+		return "", "", false
+	}
+	return parts[0] + "/" + parts[1], tag, true
+}
+
+// githubLoginRe matches a Github user or organization name.
+var githubLoginRe = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9]|-[A-Za-z0-9]){0,38}$`)
+
+// githubLogin returns the path of a Github profile URI such as
+// https://github.com/octocat, or "" when uri is not on github.com.
+func githubLogin(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || (u.Host != "github.com" && u.Host != "www.github.com") {
+		return ""
+	}
+	return strings.Trim(u.Path, "/")
+}
+
+// githubAvatar returns the avatar image URL of the Github profile at uri,
+// such as https://github.com/octocat, or "" when uri is not a profile.
+func githubAvatar(uri string) string {
+	login := githubLogin(uri)
+	if !githubLoginRe.MatchString(login) {
+		return ""
+	}
+	return "https://github.com/" + login + ".png"
+}
+
+// compareURLs returns the Github compare view URL from the previous release
+// to each entry, by date, keyed by entry ID. The oldest entry, and entries
+// without a Github release link, get none.
+func compareURLs(entries []Entry) map[string]string {
+	sorted := append([]Entry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return dateLess(sorted[i].Date, sorted[j].Date, "asc")
+	})
+
+	urls := make(map[string]string)
+	for i := 1; i < len(sorted); i++ {
+		repo, tag, ok := releaseTag(sorted[i].Links.Alternate().Href)
+		if !ok {
+			continue
+		}
+		prevRepo, prevTag, ok := releaseTag(sorted[i-1].Links.Alternate().Href)
+		if !ok || prevRepo != repo {
+			continue
+		}
+		urls[sorted[i].ID] = "https://github.com/" + repo + "/compare/" + url.PathEscape(prevTag) + "..." + url.PathEscape(tag)
+	}
+	return urls
+}
+
+// fetchReleaseHTML fetches the rendered html body of a release from the
+// Github API. token is optional but raises the API rate limit.
+func fetchReleaseHTML(ctx context.Context, repo, tag, token string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", githubAPI+"/repos/"+repo+"/releases/tags/"+url.PathEscape(tag), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github.html+json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching release %s of %s: %s", tag, repo, resp.Status)
+	}
+
+	var release struct {
+		BodyHTML string `json:"body_html"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", err
+	}
+	return release.BodyHTML, nil
+}
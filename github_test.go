@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFetchMissingMarkdownBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/releases/tags/v1.2.0" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"body_html": "<h2>What's Changed</h2>\n<p>Faster <strong>imports</strong><img src=\"https://img.shields.io/badge/x\"></p>"}`))
+	}))
+	defer srv.Close()
+	defer func(api string) { githubAPI = api }(githubAPI)
+	githubAPI = srv.URL
+
+	feed := []byte(`[{"id": 1, "html_url": "https://github.com/owner/repo/releases/tag/v1.2.0", "tag_name": "v1.2.0", "body": "", "published_at": "2023-04-02T01:30:00Z", "author": {"login": "alice"}}]`)
+	dir := convert(t, feed, "-fetch-missing-bodies", "-convert", "-strip-selector", "img[src*=shields.io]")
+	post, err := os.ReadFile(filepath.Join(dir, "v1.2.0.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The fetched body is html, and gets converted and stripped like the
+	// body of a feed entry.
+	if !strings.Contains(string(post), "## What's Changed") || !strings.Contains(string(post), "Faster **imports**") {
+		t.Errorf("the fetched body was not converted:\n%s", post)
+	}
+	if strings.Contains(string(post), "<h2>") || strings.Contains(string(post), "shields.io") {
+		t.Errorf("the post contains the html of the fetched body:\n%s", post)
+	}
+}
@@ -1,3 +1,12 @@
 module github.com/displague/releasetoblog
 
-require github.com/lunny/html2md v0.0.0-20181018071239-7d234de44546
+go 1.18
+
+require (
+	github.com/BurntSushi/toml v1.2.1
+	github.com/lunny/html2md v0.0.0-20181018071239-7d234de44546
+	github.com/yuin/goldmark-emoji v1.0.1
+	golang.org/x/net v0.30.0
+	golang.org/x/text v0.19.0
+	gopkg.in/yaml.v3 v3.0.1
+)
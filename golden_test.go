@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden with the current output")
+
+// goldenTests convert a feed in testdata with each combination of flags,
+// comparing the files written with those in testdata/golden/<name>.
+var goldenTests = []struct {
+	name string
+	feed string
+	args []string
+}{
+	{"default", "releases.atom", nil},
+	{"convert", "releases.atom", []string{"-convert"}},
+	{"conventional", "releases.atom", []string{"-convert", "-group-by-conventional", "-auto-more"}},
+	{"dates", "releases.atom", []string{"-date-format", "date", "-timezone", "Asia/Tokyo", "-date-folders", "month"}},
+	{"index", "releases.atom", []string{"-index", "-sort", "asc"}},
+	{"seo", "releases.atom", []string{"-convert", "-seo", "-words"}},
+	{"links", "releases.atom", []string{"-canonical", "-compare-url", "-full-changelog", "-strip-full-changelog"}},
+	{"key-map", "releases.atom", []string{"-key-map", "changelog=releaseNotes", "-key-map", "version=release", "-mentions"}},
+	{"tags", "releases.atom", []string{"-tag-template", "{{ .Repo }}, v{{ majorVersion .Version }}", "-series-from-repo"}},
+	{"title-date", "releases.atom", []string{"-title-date", "-title-date-sep", " - ", "-no-version", "-prerelease-expiry-days", "30"}},
+	{"digest", "releases.atom", []string{"-entries-per-file", "2"}},
+	{"extra", "releases.atom", []string{"-extra", "weight: 10", "-lang", "de", "-cover", "-sidecar-json"}},
+	{"keep-html", "releases.atom", []string{"-convert", "-keep-html", "sidecar"}},
+}
+
+func TestGolden(t *testing.T) {
+	for _, tt := range goldenTests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := os.ReadFile(filepath.Join("testdata", tt.feed))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := readTree(t, convert(t, b, tt.args...))
+			golden := filepath.Join("testdata", "golden", tt.name)
+
+			if *update {
+				if err := os.RemoveAll(golden); err != nil {
+					t.Fatal(err)
+				}
+				for name, content := range got {
+					path := filepath.Join(golden, name)
+					if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+						t.Fatal(err)
+					}
+					if err := os.WriteFile(path, content, 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				return
+			}
+
+			want := readTree(t, golden)
+			if gotNames, wantNames := names(got), names(want); !reflect.DeepEqual(gotNames, wantNames) {
+				t.Fatalf("wrote %q, want %q", gotNames, wantNames)
+			}
+			for name, content := range want {
+				if !bytes.Equal(got[name], content) {
+					t.Errorf("%s differs from %s:\n%s\nwant:\n%s", name, filepath.Join(golden, name), got[name], content)
+				}
+			}
+		})
+	}
+}
+
+// readTree returns the contents of the files under dir by their slash
+// separated path relative to dir.
+func readTree(t *testing.T, dir string) map[string][]byte {
+	t.Helper()
+	files := map[string][]byte{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = b
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func names(files map[string][]byte) []string {
+	var s []string
+	for name := range files {
+		s = append(s, name)
+	}
+	sort.Strings(s)
+	return s
+}
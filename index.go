@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// indexPost is a post listed on the index page.
+type indexPost struct {
+	Entry
+	// Link is the post's URL relative to the index page.
+	Link string
+}
+
+// indexData is passed to the index template.
+type indexData struct {
+	Title string
+	// Subtitle and Updated are the feed's <subtitle> and <updated>.
+	Subtitle string
+	Updated  Date
+	Posts    []indexPost
+}
+
+var indexTempl = `---
+title: "{{ yaml .Title }}"
+{{- if .Subtitle }}
+description: "{{ yaml .Subtitle }}"
+{{- end }}
+{{- if not .Updated.IsZero }}
+lastmod: {{ .Updated }}
+{{- end }}
+---
+{{ range .Posts }}
+- [{{ .Title }}]({{ .Link }}) ({{ ymd .Date }})
+{{- end }}
+`
+
+var indexT = template.Must(template.New("index").Funcs(funcMap).Parse(indexTempl))
+
+// indexName is the name of the index page, a Hugo section page, without
+// its extension.
+const indexName = "_index"
+
+// addIndexPost records an entry written to out for its index page.
+func addIndexPost(posts map[string][]indexPost, out output, e Entry) {
+	link := filepath.ToSlash(strings.TrimSuffix(out.filename(e, ".md"), out.langSuffix()+".md")) + "/"
+	posts[out.dir] = append(posts[out.dir], indexPost{Entry: e, Link: link})
+}
+
+// renderIndex renders the index page of the feed for posts, ordered by date
+// according to order: asc, desc, or empty to keep the feed order.
+func renderIndex(exp Export, posts []indexPost, order string) ([]byte, error) {
+	sorted := append([]indexPost(nil), posts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return dateLess(sorted[i].Date, sorted[j].Date, order)
+	})
+
+	var buf bytes.Buffer
+	if err := indexT.Execute(&buf, indexData{
+		Title:    exp.Title,
+		Subtitle: strings.Join(strings.Fields(exp.Subtitle), " "),
+		Updated:  exp.Updated,
+		Posts:    sorted,
+	}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// indexPath returns the path of the index page of an output.
+func indexPath(out output) string {
+	return filepath.Join(out.dir, indexName+out.langSuffix()+".md")
+}
+
+// validOrder reports whether order is an accepted -sort value.
+func validOrder(order string) bool {
+	return order == "" || order == "asc" || order == "desc"
+}
+
+// dateLess orders dates oldest first for asc and newest first for desc. Any
+// other order considers all dates equal, keeping the feed order when used
+// with a stable sort.
+func dateLess(a, b Date, order string) bool {
+	switch order {
+	case "asc":
+		return a.Time().Before(b.Time())
+	case "desc":
+		return a.Time().After(b.Time())
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// keyMap renames the top level frontmatter keys of rendered posts, from the
+// name the built-in templates use to the one given with -key-map.
+var keyMap map[string]string
+
+// yamlKeyRe matches the frontmatter key names -key-map accepts, plain YAML
+// keys that need no quoting.
+var yamlKeyRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// parseKeyMap parses the canonical=custom values of -key-map.
+func parseKeyMap(specs []string) (map[string]string, error) {
+	keys := make(map[string]string)
+	for _, spec := range specs {
+		i := strings.Index(spec, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid -key-map %q: expected canonical=custom", spec)
+		}
+		from, to := strings.TrimSpace(spec[:i]), strings.TrimSpace(spec[i+1:])
+		if !reservedKeys[from] {
+			return nil, fmt.Errorf("invalid -key-map %q: %q is not a frontmatter key set by releasetoblog", spec, from)
+		}
+		if !yamlKeyRe.MatchString(to) {
+			return nil, fmt.Errorf("invalid -key-map %q: %q is not a plain YAML key", spec, to)
+		}
+		keys[from] = to
+	}
+
+	// The renamed keys must not clash with each other or with a built-in key
+	// that keeps its name.
+	seen := make(map[string]string)
+	for from, to := range keys {
+		if prev, ok := seen[to]; ok {
+			return nil, fmt.Errorf("invalid -key-map: %q and %q are both renamed to %q", prev, from, to)
+		}
+		seen[to] = from
+		if _, renamed := keys[to]; reservedKeys[to] && !renamed {
+			return nil, fmt.Errorf("invalid -key-map: %q is renamed to %q, which is already set by releasetoblog", from, to)
+		}
+	}
+	return keys, nil
+}
+
+// mappedKey returns the name key is written under.
+func mappedKey(key string) string {
+	if to, ok := keyMap[key]; ok {
+		return to
+	}
+	return key
+}
+
+// renameKeys applies keyMap to the top level keys of the YAML frontmatter
+// of a rendered post.
+func renameKeys(post []byte) []byte {
+	if len(keyMap) == 0 {
+		return post
+	}
+	fm, body, ok := splitFrontmatter(string(post))
+	if !ok {
+		return post
+	}
+
+	lines := strings.SplitAfter(fm, "\n")
+	for i, line := range lines {
+		m := extraKeyRe.FindStringSubmatch(strings.TrimRight(line, "\n"))
+		if m == nil {
+			continue
+		}
+		if to, ok := keyMap[m[1]]; ok {
+			lines[i] = to + line[len(m[1]):]
+		}
+	}
+	return []byte("---\n" + strings.Join(lines, "") + "---\n" + body)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// issueLink is passed to the -relative-links template.
+type issueLink struct {
+	// Repo is the owner/repo the issue belongs to.
+	Repo string
+	// Kind is "issues" or "pull".
+	Kind   string
+	Number string
+}
+
+var githubIssueRe = regexp.MustCompile(`^https://github\.com/([^/]+/[^/]+)/(issues|pull)/(\d+)/?(#.*)?$`)
+
+// rewriteIssueLinks points the links to Github issues and pull requests in
+// an html body, and bare #123 references to issues of repo, at the URL
+// rendered by tmpl. It reports how many links it rewrote.
+func rewriteIssueLinks(body string, tmpl *template.Template, repo string) (string, int, error) {
+	nodes, err := parseBody(body)
+	if err != nil {
+		return "", 0, err
+	}
+
+	render := func(l issueLink) (string, error) {
+		var buf bytes.Buffer
+		err := tmpl.Execute(&buf, l)
+		return strings.TrimSpace(buf.String()), err
+	}
+
+	n := 0
+	var walk func(node *html.Node) error
+	walk = func(node *html.Node) error {
+		if node.Type == html.ElementNode {
+			switch node.Data {
+			case "a":
+				for i, a := range node.Attr {
+					m := githubIssueRe.FindStringSubmatch(a.Val)
+					if a.Key != "href" || m == nil {
+						continue
+					}
+					href, err := render(issueLink{Repo: m[1], Kind: m[2], Number: m[3]})
+					if err != nil {
+						return err
+					}
+					node.Attr[i].Val = href + m[4]
+					n++
+				}
+				return nil
+			case "code", "pre":
+				return nil
+			}
+		}
+		if node.Type == html.TextNode && repo != "" {
+			return linkReferences(node, repo, render, &n)
+		}
+		for c := node.FirstChild; c != nil; {
+			next := c.NextSibling
+			if err := walk(c); err != nil {
+				return err
+			}
+			c = next
+		}
+		return nil
+	}
+
+	root := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	for _, node := range nodes {
+		root.AppendChild(node)
+	}
+	if err := walk(root); err != nil {
+		return "", 0, err
+	}
+
+	var kept []*html.Node
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		kept = append(kept, c)
+	}
+	for _, node := range kept {
+		root.RemoveChild(node)
+	}
+	out, err := renderNodes(kept)
+	return out, n, err
+}
+
+// linkReferences replaces a text node holding #123 references with the text
+// around them and links to the rendered issue URLs.
+func linkReferences(text *html.Node, repo string, render func(issueLink) (string, error), n *int) error {
+	matches := referenceRe.FindAllStringSubmatchIndex(text.Data, -1)
+	if matches == nil || text.Parent == nil {
+		return nil
+	}
+
+	parent, data, last := text.Parent, text.Data, 0
+	for _, m := range matches {
+		// m[2]:m[3] is the number; the reference starts at its #.
+		start := m[2] - 1
+		href, err := render(issueLink{Repo: repo, Kind: "issues", Number: data[m[2]:m[3]]})
+		if err != nil {
+			return err
+		}
+		parent.InsertBefore(&html.Node{Type: html.TextNode, Data: data[last:start]}, text)
+		a := &html.Node{Type: html.ElementNode, Data: "a", DataAtom: atom.A, Attr: []html.Attribute{{Key: "href", Val: href}}}
+		a.AppendChild(&html.Node{Type: html.TextNode, Data: data[start:m[3]]})
+		parent.InsertBefore(a, text)
+		last = m[3]
+		*n++
+	}
+	text.Data = data[last:]
+	return nil
+}
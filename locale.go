@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/text/language"
+)
+
+// monthNames holds the month names and long date pattern of a language. The
+// pattern receives the month name, day and year in that order.
+type monthNames struct {
+	months  [12]string
+	pattern string
+}
+
+// locales are the languages supported by humandate.
+var locales = map[language.Tag]monthNames{
+	language.English: {
+		[12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		"%[1]s %[2]d, %[3]d",
+	},
+	language.German: {
+		[12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		"%[2]d. %[1]s %[3]d",
+	},
+	language.French: {
+		[12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		"%[2]d %[1]s %[3]d",
+	},
+	language.Spanish: {
+		[12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		"%[2]d de %[1]s de %[3]d",
+	},
+	language.Italian: {
+		[12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		"%[2]d %[1]s %[3]d",
+	},
+	language.Portuguese: {
+		[12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		"%[2]d de %[1]s de %[3]d",
+	},
+	language.Dutch: {
+		[12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		"%[2]d %[1]s %[3]d",
+	},
+	language.Japanese: {
+		[12]string{"1月", "2月", "3月", "4月", "5月", "6月", "7月", "8月", "9月", "10月", "11月", "12月"},
+		"%[3]d年%[1]s%[2]d日",
+	},
+}
+
+// dateLocale is the language of dates written by humandate, set with -locale.
+var dateLocale = language.English
+
+// parseLocale returns the supported language closest to the BCP 47 tag s,
+// such as en-US or de.
+func parseLocale(s string) (language.Tag, error) {
+	tag, err := language.Parse(s)
+	if err != nil {
+		return language.Und, err
+	}
+
+	supported := make([]language.Tag, 0, len(locales))
+	supported = append(supported, language.English)
+	for t := range locales {
+		if t != language.English {
+			supported = append(supported, t)
+		}
+	}
+	_, i, confidence := language.NewMatcher(supported).Match(tag)
+	if confidence == language.No {
+		return language.Und, fmt.Errorf("no month names for %s", tag)
+	}
+	return supported[i], nil
+}
+
+// humandate formats a date with the month name, e.g. "April 1, 2023", in the
+// language set with -locale.
+func humandate(date Date) string {
+	t := date.Time()
+	names := locales[dateLocale]
+	return fmt.Sprintf(names.pattern, names.months[t.Month()-1], t.Day(), t.Year())
+}
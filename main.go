@@ -1,32 +1,108 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"path"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
 	"text/template"
 	"time"
 	"unicode"
+	"unicode/utf16"
 
-	"github.com/lunny/html2md"
+	"golang.org/x/net/html"
+	"golang.org/x/text/language"
 )
 
 type Date time.Time
 
+// dateLayout is the layout used when rendering dates into frontmatter.
+var dateLayout = time.RFC3339
+
+// dateLocation, when set, is the time zone dates are converted to before
+// they are rendered. Otherwise the feed's offset is kept.
+var dateLocation *time.Location
+
+// Time returns the effective time of the date: converted to the -timezone
+// zone when one is set, otherwise with the feed's own offset. The frontmatter
+// date, the date in file names and the -date-folders path are all derived
+// from it, so they always agree on the day of a release.
+func (d Date) Time() time.Time {
+	if dateLocation != nil {
+		return time.Time(d).In(dateLocation)
+	}
+	return time.Time(d)
+}
+
 func (d Date) String() string {
-	return time.Time(d).Format(time.RFC3339)
+	return d.Time().Format(dateLayout)
+}
+
+// parseDateFormat resolves the -date-format keywords to a time layout. Any
+// other value is used as a Go time layout as-is.
+func parseDateFormat(s string) string {
+	switch strings.ToLower(s) {
+	case "", "rfc3339":
+		return time.RFC3339
+	case "date":
+		return "2006-01-02"
+	}
+	return s
+}
+
+// IsZero reports whether the date was not set, e.g. when the element was
+// missing from the feed.
+func (d Date) IsZero() bool {
+	return time.Time(d).IsZero()
+}
+
+// feedDateLayouts are the date formats of Atom (RFC 3339) and RSS (RFC 822
+// with four digit years, as RFC 1123) feeds.
+var feedDateLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
 }
 
 func (d *Date) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
 	var v string
 	dec.DecodeElement(&v, &start)
-	t, err := time.Parse(time.RFC3339Nano, v)
+	v = strings.TrimSpace(v)
+	t, err := time.Parse(feedDateLayouts[0], v)
+	for _, layout := range feedDateLayouts[1:] {
+		if err == nil {
+			break
+		}
+		if rt, rerr := time.Parse(layout, v); rerr == nil {
+			t, err = rt, nil
+		}
+	}
 	if err != nil {
 		return err
 	}
@@ -37,158 +113,2438 @@ func (d *Date) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
 type Author struct {
 	Name string `xml:"name"`
 	Uri  string `xml:"uri"`
+	// Avatar is the URL of the author's Github avatar, set with -avatar.
+	Avatar string `xml:"-"`
 }
 
 type Export struct {
-	XMLName xml.Name `xml:"feed"`
-	Title   string   `xml:"title"`
-	Entries []Entry  `xml:"entry"`
+	XMLName  xml.Name `xml:"feed"`
+	ID       string   `xml:"id"`
+	Title    string   `xml:"title"`
+	Subtitle string   `xml:"subtitle"`
+	Updated  Date     `xml:"updated"`
+	Entries  []Entry  `xml:"entry"`
 }
 
 type Entry struct {
-	ID          string `xml:"id"`
-	Updated     Date   `xml:"updated"`
-	Title       string `xml:"title"`
-	Content     string `xml:"content"`
-	Links       Links  `xml:"link"`
-	Author      Author `xml:"author"`
-	Description string
-	Extra       string
-	Repo        string
+	ID            string `xml:"id"`
+	Updated       Date   `xml:"updated"`
+	Published     Date   `xml:"published"`
+	Title         string `xml:"title"`
+	Content       string `xml:"content"`
+	Links         Links  `xml:"link"`
+	Author        Author `xml:"author"`
+	Date          Date
+	Version       string
+	Prerelease    bool
+	Draft         bool
+	ExpiryDate    *Date
+	PublishDate   *Date
+	FeedTitle     string
+	Description   string
+	TitleDate     string
+	Extra         string
+	Repo          string
+	RepoPrefix    string
+	Changelog     []string
+	Series        []string
+	Tags          []string
+	Cover         string
+	SEO           bool
+	CompareURL    string
+	FullChangelog string
+	CanonicalURL  string
+	Lang          string
+	Contributors  []string
+	References    []string
+	Assets        []Asset
+	ContentHTML   string
+	Stamp         string
+	Words         int
+	ReadingTime   int
+
+	// markdown is set for bodies that are markdown already rather than html.
+	markdown bool
+	// slug overrides the file name derived from the title, from -slug-map.
+	slug string
+	// sources are the repos of the releases merged into a digest.
+	sources []string
+}
+
+// QualifiedRepo returns the repo prefixed with RepoPrefix, for use wherever
+// the repo is emitted as an identifier (changelog, tags, categories).
+func (e Entry) QualifiedRepo() string {
+	return e.RepoPrefix + e.Repo
+}
+
+// changelogs returns the changelog entries the templates list after Tools:
+// with a RepoPrefix, every repo the releases of the entry come from,
+// prefixed with it.
+func (e Entry) changelogs() []string {
+	if e.RepoPrefix == "" {
+		return nil
+	}
+	var changelogs []string
+	for _, r := range e.repos() {
+		changelogs = append(changelogs, e.RepoPrefix+r)
+	}
+	return changelogs
+}
+
+// repos returns the repos the entry's releases come from: the sources of a
+// digest, or else its Repo.
+func (e Entry) repos() []string {
+	if len(e.sources) > 0 || e.Repo == "" {
+		return e.sources
+	}
+	return []string{e.Repo}
 }
 
 type Link struct {
-	Href string `xml:"href,attr"`
-	Rel  string `xml:"rel,attr"`
-	Type string `xml:"type,attr"`
+	Href  string `xml:"href,attr"`
+	Rel   string `xml:"rel,attr"`
+	Type  string `xml:"type,attr"`
+	Title string `xml:"title,attr"`
 }
 
 type Links []Link
 
+// byRel returns the links with the given rel. A link without a rel is an
+// alternate link, as in Atom.
+func (l Links) byRel(rel string) Links {
+	var found Links
+	for _, link := range l {
+		if link.Rel == rel || (link.Rel == "" && rel == "alternate") {
+			found = append(found, link)
+		}
+	}
+	return found
+}
+
+// first returns the first link with the given rel, or an empty Link when
+// there is none.
+func (l Links) first(rel string) Link {
+	if found := l.byRel(rel); len(found) > 0 {
+		return found[0]
+	}
+	return Link{}
+}
+
+// Enclosures returns the links with rel="enclosure", used for downloadable
+// release assets.
+func (l Links) Enclosures() Links {
+	return l.byRel("enclosure")
+}
+
+// Alternate returns the first rel="alternate" link, the release page on
+// Github, or an empty Link when there is none.
+func (l Links) Alternate() Link {
+	return l.first("alternate")
+}
+
+// Self returns the first rel="self" link, the URL of the entry itself, or an
+// empty Link when there is none.
+func (l Links) Self() Link {
+	return l.first("self")
+}
+
+// Related returns the links with rel="related", such as a discussion of the
+// release.
+func (l Links) Related() Links {
+	return l.byRel("related")
+}
+
+// Asset is a downloadable file attached to a release.
+type Asset struct {
+	Name string
+	URL  string
+	Type string
+}
+
+// assets converts enclosure links to assets, naming each by its title or
+// else the last element of its URL.
+func assets(links Links) []Asset {
+	var found []Asset
+	for _, link := range links.Enclosures() {
+		name := link.Title
+		if name == "" {
+			name = path.Base(link.Href)
+			if u, err := url.Parse(link.Href); err == nil {
+				name = path.Base(u.Path)
+			}
+		}
+		found = append(found, Asset{Name: name, URL: link.Href, Type: link.Type})
+	}
+	return found
+}
+
+// markdownImageRe matches a markdown image, or an html img element in
+// markdown, capturing its source.
+var markdownImageRe = regexp.MustCompile(`!\[[^\]]*\]\(\s*<?([^)\s>]+)|<img\s[^>]*\bsrc\s*=\s*["']([^"']+)["']`)
+
+// firstImage returns the src of the first image in an html or markdown body,
+// skipping inline data URIs, or an empty string when it has none.
+func firstImage(body string, markdown bool) string {
+	if markdown {
+		for _, m := range markdownImageRe.FindAllStringSubmatch(strings.Join(markdownLines(body), "\n"), -1) {
+			if src := strings.TrimSpace(m[1] + m[2]); src != "" && !strings.HasPrefix(src, "data:") {
+				return src
+			}
+		}
+		return ""
+	}
+
+	nodes, err := parseBody(body)
+	if err != nil {
+		return ""
+	}
+
+	var src string
+	var walk func(n *html.Node) bool
+	walk = func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == "img" {
+			if v := strings.TrimSpace(attr(n, "src")); v != "" && !strings.HasPrefix(v, "data:") {
+				src = v
+				return true
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if walk(c) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, n := range nodes {
+		if walk(n) {
+			break
+		}
+	}
+	return src
+}
+
 var templ = `---
-title: "{{ .Repo }}: {{ .Title }}"
-date: {{ .Updated }}
-description: "{{ .Description }}"
+title: "{{ yaml .Repo }}: {{ yaml .Title }}{{ yaml .TitleDate }}"
+date: {{ .Date }}
+description: "{{ yaml .Description }}"
 changelog:
 - Tools
-version: "{{ .Title }}"
+{{- range .Changelog }}
+- "{{ yaml . }}"
+{{- end }}
+{{- if .Series }}
+series:
+{{- range .Series }}
+- "{{ yaml . }}"
+{{- end }}
+{{- end }}
+{{- if .Version }}
+version: "{{ yaml .Version }}"
+{{- end }}
+{{- if .Draft }}
+draft: true
+{{- end }}
+{{- if .Tags }}
+tags:
+{{- range .Tags }}
+- "{{ yaml . }}"
+{{- end }}
+{{- end }}
+{{- if .Lang }}
+lang: "{{ yaml .Lang }}"
+{{- end }}
+{{- if .CompareURL }}
+compareURL: "{{ yaml .CompareURL }}"
+{{- end }}
+{{- if .FullChangelog }}
+fullChangelog: "{{ yaml .FullChangelog }}"
+{{- end }}
+{{- if .CanonicalURL }}
+canonicalURL: "{{ yaml .CanonicalURL }}"
+{{- end }}
+{{- if .Cover }}
+cover: "{{ yaml .Cover }}"
+images:
+- "{{ yaml .Cover }}"
+{{- end }}
 author:
-  name: "{{ .Author.Name }}"
+  name: "{{ yaml .Author.Name }}"
+  {{- if .Author.Avatar }}
+  avatar: "{{ yaml .Author.Avatar }}"
+  {{- end }}
+{{- if .SEO }}
+params:
+  "og:title": "{{ yaml .Repo }}: {{ yaml .Title }}"
+  "og:description": "{{ yaml .Description }}"
+  "og:type": "article"
+  {{- if .Cover }}
+  "og:image": "{{ yaml .Cover }}"
+  {{- end }}
+{{- end }}
+{{- if .Assets }}
+assets:
+{{- range .Assets }}
+- name: "{{ yaml .Name }}"
+  url: "{{ yaml .URL }}"
+  {{- if .Type }}
+  type: "{{ yaml .Type }}"
+  {{- end }}
+{{- end }}
+{{- end }}
+{{- if .ContentHTML }}
+contentHTML: |-
+{{ indent 2 .ContentHTML }}
+{{- end }}
+{{- if .Contributors }}
+contributors:
+{{- range .Contributors }}
+- "{{ yaml . }}"
+{{- end }}
+{{- end }}
+{{- if .References }}
+references:
+{{- range .References }}
+- "{{ yaml . }}"
+{{- end }}
+{{- end }}
+{{- if .PublishDate }}
+publishDate: {{ .PublishDate }}
+{{- end }}
+{{- if .ExpiryDate }}
+expiryDate: {{ .ExpiryDate }}
+{{- end }}
+{{- if .Words }}
+words: {{ .Words }}
+readingTime: {{ .ReadingTime }}
+{{- end }}
+{{- if .Extra }}
+{{ .Extra }}
+{{- end }}
 ---
 
 {{ .Content }}
+{{- if .Stamp }}
+
+{{ .Stamp }}
+{{- end }}
 `
 
 var funcMap = template.FuncMap{
-	"ymd": yearMonthDate,
+	"ymd":          yearMonthDate,
+	"slugify":      makePath,
+	"majorVersion": majorVersion,
+	"truncate":     truncate,
+	"now":          now,
+	"indent":       indent,
+	"yaml":         yamlEscaper.Replace,
+	"humandate":    humandate,
+	"default":      defaultValue,
+	"list":         list,
+	"first":        first,
+	"last":         last,
+	"join":         join,
+	"splitList":    splitList,
+	"lower":        strings.ToLower,
+	"upper":        strings.ToUpper,
+	"trim":         strings.TrimSpace,
+	"trimPrefix":   trimPrefix,
+	"trimSuffix":   trimSuffix,
+	"replace":      replace,
+	"contains":     containsString,
+	"hasPrefix":    hasPrefix,
+	"hasSuffix":    hasSuffix,
+	"quote":        strconv.Quote,
+	"date":         formatDate,
+	"dateModify":   dateModify,
+	"reltime":      reltime,
 }
 var t = template.Must(template.New("").Funcs(funcMap).Parse(templ))
 
+// yearMonthDate returns the day of the date's effective time, as used in
+// file names and by the ymd template function.
 func yearMonthDate(date Date) string {
-	d := time.Time(date)
-	return fmt.Sprintf("%0d-%02d-%02d", d.Year(), d.Month(), d.Day())
+	return date.Time().Format("2006-01-02")
+}
+
+var majorVersionRe = regexp.MustCompile(`\d+`)
+
+// majorVersion returns the first number in a version string, e.g. "1" for
+// "v1.2.3", or an empty string when there is none.
+func majorVersion(s string) string {
+	return majorVersionRe.FindString(s)
+}
+
+// truncate shortens s to at most n runes. Its argument order allows use in
+// pipelines: {{ .Title | truncate 20 }}.
+func truncate(n int, s string) string {
+	r := []rune(s)
+	if n < 0 || len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
+
+// indent prefixes every line of s with n spaces, e.g. for YAML block
+// scalars.
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	return pad + strings.Replace(s, "\n", "\n"+pad, -1)
+}
+
+// relativeDescription is the description template of -relative-description.
+const relativeDescription = "Released {{ reltime .Date }}"
+
+// clock returns the current time. It is a variable so that it can be
+// replaced to run with a fixed time.
+var clock = time.Now
+
+func now() Date {
+	return Date(clock())
+}
+
+// loadTemplate parses a custom post template from a file, with the same
+// functions available as in the built-in template.
+func loadTemplate(filename string) (*template.Template, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(filepath.Base(filename)).Funcs(funcMap).Parse(string(b))
+}
+
+// options holds the settings shared by the convert command and the legacy
+// two argument invocation.
+type options struct {
+	convert       bool
+	force         bool
+	extra         string
+	extraFile     string
+	extraKeys     stringsFlag
+	slugMap       string
+	setTOC        bool
+	seo           bool
+	autoMore      bool
+	relativeLinks string
+	compareURL    bool
+	canonical     bool
+	includeEmpty  bool
+	validate      bool
+	check         bool
+	quiet         bool
+	avatar        bool
+	singleDoc     string
+	defaultAuthor string
+	defaultURI    string
+	relDesc       bool
+	collisions    bool
+	prereleaseDir string
+	emojify       bool
+	groupConv     bool
+	titleDate     bool
+	titleDateSep  string
+	fullLog       bool
+	stripFullLog  bool
+	idFilenames   bool
+	onlyAuthors   stringsFlag
+	exclAuthors   stringsFlag
+	prependFile   string
+	appendFile    string
+	printConfig   bool
+
+	// feed and validators identify the feed fetched for this run, to be
+	// recorded in the state file.
+	feed           string
+	validators     validators
+	futureDrafts   bool
+	lang           string
+	perFile        int
+	inputFormat    string
+	trailingNL     string
+	cover          bool
+	dateFolders    string
+	setMath        bool
+	archive        string
+	repoPrefix     string
+	dateFormat     string
+	match          string
+	exclude        string
+	words          bool
+	wpm            int
+	dateSource     string
+	keepCase       bool
+	diff           bool
+	template       string
+	timezone       string
+	locale         string
+	keepHTML       string
+	sidecarJSON    bool
+	slugSep        string
+	stdout         bool
+	limit          int
+	repo           string
+	stamp          bool
+	outputs        stringsFlag
+	maxBodyLen     int
+	series         bool
+	yes            bool
+	maxForeign     int
+	mentions       bool
+	strip          stringsFlag
+	trimPrefixes   stringsFlag
+	repoMap        stringsFlag
+	keyMap         stringsFlag
+	templateRules  stringsFlag
+	scrub          bool
+	sanitize       bool
+	sanitizeAllow  stringsFlag
+	scrubPatterns  stringsFlag
+	descTemplate   string
+	tagTemplate    string
+	sort           string
+	index          bool
+	indexSort      string
+	stateFile      string
+	prune          pruneFlag
+	maxAge         ageFlag
+	expiryDays     int
+	collapse       bool
+	collapseWindow time.Duration
+	gitCommit      bool
+	gitAuthor      string
+	noVersion      bool
+	changelogFile  string
+	fetchBodies    bool
+	summaryFile    string
+	merge          bool
+	failOnWarn     bool
+	list           bool
+	token          string
+}
+
+// stringsFlag collects the values of a repeatable flag.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+func (f *stringsFlag) Get() interface{} {
+	return []string(*f)
+}
+
+// conditionalFetch reports whether the feed may be fetched only if it
+// changed since the last run: the run writes posts, with nothing in them
+// depending on the time rather than on the feed, so that an unchanged feed
+// leaves nothing to do.
+func (o *options) conditionalFetch() bool {
+	return o.stateFile != "" && !o.force && o.archive == "" && !o.stdout && !o.diff && !o.check && !o.list &&
+		!o.collisions && o.maxAge == 0 && o.expiryDays == 0 && !o.relDesc && !o.futureDrafts
+}
+
+// filtered reports whether the run only handles some of the releases of the
+// feed.
+func (o *options) filtered() bool {
+	return o.match != "" || o.exclude != "" || o.limit > 0 || len(o.onlyAuthors) > 0 || len(o.exclAuthors) > 0
+}
+
+// needsDir reports whether the target directory argument is required. It is
+// optional when posts go to an archive, stdout or -output directories.
+func (o *options) needsDir() bool {
+	return o.archive == "" && !o.stdout && !o.list && !o.collisions && len(o.outputs) == 0
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	fs.BoolVar(&o.convert, "convert", false, "convert release html back to markdown")
+	fs.BoolVar(&o.force, "force", false, "overwrite existing files")
+	fs.BoolVar(&o.merge, "merge-frontmatter", false, "update existing posts but keep frontmatter keys added to them by hand")
+	fs.BoolVar(&o.printConfig, "print-config", false, "print the effective settings as JSON and exit")
+	fs.BoolVar(&o.quiet, "quiet", false, "do not report the progress of long runs on stderr")
+	fs.BoolVar(&o.failOnWarn, "fail-on-warn", false, "exit with an error after processing everything if there were warnings, for CI")
+	fs.BoolVar(&o.validate, "validate", false, "parse the frontmatter of each post with a YAML or TOML parser and warn about invalid ones")
+	fs.BoolVar(&o.yes, "yes", false, "write into the target directory even if it holds many other files")
+	fs.IntVar(&o.maxForeign, "max-foreign-files", 50, "refuse to write into a directory holding more than this many non-post files, unless -yes or -force is set")
+	fs.StringVar(&o.extra, "extra", "", "additional metadata to set in frontmatter, as \"key: value\" lines")
+	fs.StringVar(&o.extraFile, "extra-file", "", "file of additional \"key: value\" frontmatter lines")
+	fs.Var(&o.extraKeys, "extra-key", "allow another -extra frontmatter key, as key:type with type string, bool, int, number, date, list, map or any (repeatable)")
+	fs.BoolVar(&o.idFilenames, "id-filenames", false, "name files after a hash of the release ID, which never changes, instead of the title")
+	fs.StringVar(&o.slugMap, "slug-map", "", "file of \"id-or-title = slug\" lines pinning the file names of some releases")
+	fs.StringVar(&o.prependFile, "prepend-file", "", "insert this template file before the body of each post, e.g. a notice")
+	fs.StringVar(&o.appendFile, "append-file", "", "insert this template file after the body of each post, e.g. a call to subscribe")
+	fs.BoolVar(&o.setTOC, "set-toc", false, "set toc: true in the frontmatter, to show a table of contents")
+	fs.BoolVar(&o.setMath, "set-math", false, "set math: true in the frontmatter, to enable math rendering")
+	fs.StringVar(&o.relativeLinks, "relative-links", "", "Go template for the URL of issue and pull request links and #123 references, e.g. '/issues/{{ .Number }}'")
+	fs.BoolVar(&o.compareURL, "compare-url", false, "set compareURL to the Github comparison with the previous release")
+	fs.BoolVar(&o.canonical, "canonical", false, "set canonicalURL to the release page, for sites that syndicate the Github releases")
+	fs.BoolVar(&o.fullLog, "full-changelog", false, "set fullChangelog to the URL of the \"Full Changelog\" line of Github's generated release notes")
+	fs.BoolVar(&o.stripFullLog, "strip-full-changelog", false, "with -full-changelog, also remove the \"Full Changelog\" line from the body")
+	fs.BoolVar(&o.seo, "seo", false, "add OpenGraph og:title, og:description and og:type params for social sharing")
+	fs.BoolVar(&o.emojify, "emojify", false, "replace emoji shortcodes such as :rocket: in the release notes with unicode emoji")
+	fs.BoolVar(&o.groupConv, "group-by-conventional", false, "regroup bullets with conventional commit prefixes such as feat: and fix: under Features, Fixes and Other headings")
+	fs.BoolVar(&o.autoMore, "auto-more", false, "insert a <!--more--> summary divider after the first paragraph of releases without one")
+	fs.BoolVar(&o.cover, "cover", false, "set cover and images in the frontmatter to the first image of the release notes")
+	fs.StringVar(&o.defaultAuthor, "default-author", "", "author name of releases whose feed entry has none, e.g. a release bot")
+	fs.StringVar(&o.defaultURI, "default-author-uri", "", "author URI of releases that get the -default-author")
+	fs.BoolVar(&o.avatar, "avatar", false, "set author.avatar in the frontmatter to the Github avatar of the release author")
+	fs.StringVar(&o.archive, "archive", "", "write posts into this .zip or .tar.gz file instead of the target directory")
+	fs.StringVar(&o.repo, "repo", "", "repo name for all entries (default derived from the feed title)")
+	fs.StringVar(&o.repoPrefix, "repo-prefix", "", "prefix for the repo in the changelog list, e.g. github.com/")
+	fs.BoolVar(&o.titleDate, "title-date", false, "append the release date to post titles")
+	fs.StringVar(&o.titleDateSep, "title-date-sep", ": ", "separator between the title and the date added by -title-date, e.g. \" - \" for titles containing colons")
+	fs.StringVar(&o.descTemplate, "description-template", "", "Go template for the description, e.g. 'Release {{ .Version }} of {{ .Repo }}'")
+	fs.BoolVar(&o.relDesc, "relative-description", false, "describe posts by their age, e.g. \"Released 3 days ago\", which changes from run to run")
+	fs.StringVar(&o.tagTemplate, "tag-template", "", "Go template for the tags, rendering a comma or newline separated list, e.g. '{{ .Repo }}, v{{ majorVersion .Version }}'")
+	fs.BoolVar(&o.noVersion, "no-version", false, "leave the version field out of the frontmatter")
+	fs.BoolVar(&o.series, "series-from-repo", false, "add each post to a series named after the repo")
+	fs.StringVar(&o.dateFormat, "date-format", "rfc3339", "frontmatter date format: rfc3339, date, or a Go time layout")
+	fs.StringVar(&o.match, "match", "", "only include entries whose title matches this regexp")
+	fs.StringVar(&o.exclude, "exclude", "", "skip entries whose title matches this regexp")
+	fs.Var(&o.onlyAuthors, "only-authors", "only include releases by this author name or Github login, case-insensitive (repeatable)")
+	fs.Var(&o.exclAuthors, "exclude-authors", "skip releases by this author name or Github login, e.g. github-actions[bot] (repeatable)")
+	fs.IntVar(&o.maxBodyLen, "max-body-len", 0, "truncate converted bodies to this many characters, linking to the release (0 for unlimited)")
+	fs.Var(&o.strip, "strip-selector", "remove html elements matching this selector, e.g. img[src*=shields.io], before conversion (repeatable)")
+	fs.BoolVar(&o.sanitize, "sanitize", false, "remove html elements and attributes outside an allowlist, such as scripts and event handlers")
+	fs.Var(&o.sanitizeAllow, "sanitize-allow", "also allow this element with -sanitize, as tag or tag:attr,attr (repeatable)")
+	fs.BoolVar(&o.scrub, "scrub", false, "redact email addresses and internal hostnames from the release notes")
+	fs.Var(&o.scrubPatterns, "scrub-pattern", "also redact matches of this regular expression with -scrub (repeatable)")
+	fs.Var(&o.trimPrefixes, "trim-release-prefix", "prefix to remove from the feed title to get the repo name (repeatable, default \"Release notes from \")")
+	fs.Var(&o.repoMap, "repo-map", "rename a repo in the posts, as old=new, e.g. after an upstream rename (repeatable)")
+	fs.Var(&o.keyMap, "key-map", "rename a frontmatter key set by releasetoblog, as canonical=custom, e.g. changelog=releaseNotes (repeatable)")
+	fs.BoolVar(&o.mentions, "mentions", false, "list @mentions as contributors and #123 references in frontmatter")
+	fs.BoolVar(&o.words, "words", false, "add words and readingTime (minutes) to frontmatter")
+	fs.IntVar(&o.wpm, "wpm", 200, "words per minute used to compute readingTime")
+	fs.StringVar(&o.dateSource, "date-source", "updated", "entry date to use for the post date: updated or published")
+	fs.BoolVar(&o.keepCase, "preserve-case", false, "keep the title's letter case in file names")
+	fs.BoolVar(&o.diff, "diff", false, "print a unified diff against existing files instead of writing")
+	fs.BoolVar(&o.check, "check", false, "write nothing, but exit with status 1 listing the posts that are missing or would change, for CI and pre-commit hooks")
+	fs.StringVar(&o.template, "template", "", "render posts with this Go template file instead of the built-in one")
+	fs.StringVar(&o.trailingNL, "trailing-newline", "one", "end posts with exactly one newline, none, or raw to keep the template output as is")
+	fs.Var(&o.templateRules, "template-for", "render entries matching a title regexp, or @prerelease, with another template, as predicate=file (repeatable)")
+	fs.StringVar(&o.timezone, "timezone", "", "IANA time zone to render dates in, e.g. Europe/Berlin (default the feed's)")
+	fs.StringVar(&o.locale, "locale", "en", "language of the month names written by the humandate template function, e.g. de or fr-CA")
+	fs.StringVar(&o.slugSep, "slug-separator", "-", "character replacing spaces in file names: -, _ or .")
+	fs.StringVar(&o.dateFolders, "date-folders", "", "nest posts in folders by release date: year, month (2023/04) or day (2023/04/01)")
+	fs.StringVar(&o.lang, "lang", "", "language of the posts, set as lang in the frontmatter and added to file names as slug.<lang>.md")
+	fs.BoolVar(&o.list, "list", false, "print a table of the selected releases and exit without writing posts")
+	fs.BoolVar(&o.collisions, "check-collisions", false, "report releases whose posts would be written to the same file and exit without writing")
+	fs.BoolVar(&o.stdout, "stdout", false, "print posts to stdout instead of writing them to the target directory")
+	fs.IntVar(&o.limit, "limit", 0, "only convert the first N entries (0 for all)")
+	fs.Var(&o.outputs, "output", "also write posts as format:dir, where format is hugo, jekyll or a template file (repeatable)")
+	fs.StringVar(&o.sort, "sort", "", "process entries by date: asc (oldest first) or desc (default feed order)")
+	fs.BoolVar(&o.index, "index", false, "also write an _index.md listing the posts")
+	fs.StringVar(&o.indexSort, "index-sort", "", "order of the _index.md list: asc or desc (default same as -sort)")
+	fs.BoolVar(&o.futureDrafts, "future-drafts", false, "write releases dated in the future as drafts, with publishDate set to their date")
+	fs.IntVar(&o.expiryDays, "prerelease-expiry-days", 0, "set expiryDate this many days after the date of pre-releases (0 to disable)")
+	fs.StringVar(&o.prereleaseDir, "prerelease-dir", "", "write the posts of pre-releases to this directory instead of the target directory, e.g. content/prereleases")
+	fs.BoolVar(&o.collapse, "collapse-patches", false, "merge patch releases of the same minor version into one post")
+	fs.IntVar(&o.perFile, "entries-per-file", 0, "write digests of this many releases each instead of a post per release")
+	fs.DurationVar(&o.collapseWindow, "collapse-window", 24*time.Hour, "maximum time between patch releases merged by -collapse-patches")
+	fs.StringVar(&o.inputFormat, "input-format", "", "format of the input: atom, rss for an RSS 2.0 feed, or json for a saved Github API releases response (default detected)")
+	fs.BoolVar(&o.fetchBodies, "fetch-missing-bodies", false, "fetch the notes of releases with an empty body from the Github API")
+	fs.BoolVar(&o.includeEmpty, "include-empty-bodies", false, "write posts for releases whose notes are empty, which are skipped by default")
+	fs.StringVar(&o.token, "token", os.Getenv("GITHUB_TOKEN"), "Github API token (default $GITHUB_TOKEN)")
+	fs.StringVar(&o.changelogFile, "changelog-append", "", "also add a section per release to this Keep a Changelog file, e.g. CHANGELOG.md")
+	fs.StringVar(&o.summaryFile, "summary-file", "", "also write a plain text llms.txt style summary of all releases to this file")
+	fs.StringVar(&o.singleDoc, "single-doc", "", "also write all releases as sections of this one markdown file, e.g. to print it with pandoc")
+	fs.BoolVar(&o.gitCommit, "git-commit", false, "commit the written posts to the git repository of the target directory")
+	fs.StringVar(&o.gitAuthor, "git-author", "", "author of the -git-commit commit, as \"Name <email>\" (default git's configured user)")
+	fs.StringVar(&o.stateFile, "state", "", "JSON file remembering the posts written for each release")
+	fs.Var(&o.prune, "prune", "delete posts of releases no longer in the feed, or with -prune=soft mark them as drafts (requires -state)")
+	fs.Var(&o.maxAge, "max-age", "skip releases older than this, e.g. 720h or 30d, and delete the posts written for them (requires -state)")
+	fs.BoolVar(&o.stamp, "stamp", false, "end each post with a comment noting how and when it was generated")
+	fs.StringVar(&o.keepHTML, "keep-html", "", "also keep the release html: frontmatter (as contentHTML) or sidecar (as <slug>.html)")
+	fs.BoolVar(&o.sidecarJSON, "sidecar-json", false, "also write the metadata of each post to <slug>.json beside it")
+}
+
+// secretFlags are redacted by -print-config.
+var secretFlags = map[string]bool{"token": true}
+
+// printConfig writes the value of every flag of fs, whether set on the
+// command line, from the environment or by default, as a JSON object.
+func printConfig(w io.Writer, fs *flag.FlagSet) error {
+	config := make(map[string]interface{})
+	fs.VisitAll(func(f *flag.Flag) {
+		var v interface{} = f.Value.String()
+		if g, ok := f.Value.(flag.Getter); ok {
+			v = g.Get()
+		}
+		if d, ok := v.(time.Duration); ok {
+			v = d.String()
+		}
+		if secretFlags[f.Name] && f.Value.String() != "" {
+			v = scrubReplacement
+		}
+		config[f.Name] = v
+	})
+
+	b, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+// envPrefix starts the names of the environment variables that set flags,
+// e.g. RTB_SORT for -sort.
+const envPrefix = "RTB_"
+
+// envName returns the environment variable for the flag name.
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// setFromEnv sets each flag of fs that was not passed on the command line
+// from its environment variable, when that is set and not empty.
+func setFromEnv(fs *flag.FlagSet) error {
+	passed := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { passed[f.Name] = true })
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		v := os.Getenv(envName(f.Name))
+		if err != nil || passed[f.Name] || v == "" {
+			return
+		}
+		if serr := fs.Set(f.Name, v); serr != nil {
+			err = fmt.Errorf("invalid value %q for %s: %s", v, envName(f.Name), serr)
+		}
+	})
+	return err
+}
+
+// version is set at build time with -ldflags "-X main.version=v1.2.3".
+var version = ""
+
+// generatedStamp returns the -stamp comment of the post of entry. It records
+// when the release was last updated in the feed rather than the time of the
+// run, so that posts of unchanged releases stay the same.
+func generatedStamp(exp *Export, entry Entry) string {
+	updated := entry.Updated
+	if updated.IsZero() {
+		updated = exp.Updated
+	}
+	if updated.IsZero() {
+		return fmt.Sprintf("<!-- generated by releasetoblog %s from feed %s -->", buildVersion(), exp.ID)
+	}
+	return fmt.Sprintf("<!-- generated by releasetoblog %s from feed %s at %s -->", buildVersion(), exp.ID, updated.Time().UTC().Format(time.RFC3339))
+}
+
+// buildVersion returns the version of this binary, falling back to the
+// module version recorded by go install.
+func buildVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+var commands = map[string]func(args []string){
+	"fetch":   fetchCmd,
+	"convert": convertCmd,
 }
 
 func main() {
 	log.SetFlags(0)
 
-	convert := flag.Bool("convert", false, "convert release html back to markdown")
-	force := flag.Bool("force", false, "overwrite existing files")
-	extra := flag.String("extra", "", "additional metadata to set in frontmatter")
-
-	flag.Parse()
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			cmd(os.Args[2:])
+			return
+		}
+	}
 
-	args := flag.Args()
+	legacyCmd()
+}
 
-	if len(args) != 2 {
+// legacyCmd fetches and converts a repo's feed in one step. This is the
+// original interface, equivalent to fetch followed by convert.
+func legacyCmd() {
+	o := &options{}
+	o.register(flag.CommandLine)
+	flag.Usage = func() {
 		log.Printf("Usage: %s [options] <org/repo> <targetdir>", os.Args[0])
+		log.Printf("       %s [options] -archive <file>|-stdout <org/repo>", os.Args[0])
+		log.Printf("       %s fetch [options] <org/repo>", os.Args[0])
+		log.Printf("       %s convert [options] <feed.xml> <targetdir>", os.Args[0])
 		log.Println("options:")
 		flag.PrintDefaults()
-		os.Exit(1)
+	}
+	flag.Parse()
+	if err := setFromEnv(flag.CommandLine); err != nil {
+		log.Fatal(err)
 	}
 
-	dir := args[1]
+	if o.printConfig {
+		if err := printConfig(os.Stdout, flag.CommandLine); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	info, err := os.Stat(dir)
-	if os.IsNotExist(err) {
-		if err = os.MkdirAll(dir, 0755); err == nil {
-			info, err = os.Stat(dir)
+	args := flag.Args()
+	if len(args) != 2 && !(!o.needsDir() && len(args) == 1) {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// Only a run writing every post with the settings of the last one can
+	// stop when the feed did not change since.
+	var cached validators
+	var settings string
+	if o.conditionalFetch() {
+		var err error
+		if settings, err = settingsDigest(flag.CommandLine, o, args[len(args)-1]); err != nil {
+			log.Fatal(err)
+		}
+		st, err := loadState(o.stateFile)
+		if err != nil {
+			log.Fatalf("Failed reading state file %q:\n%s", o.stateFile, err)
 		}
+		if v := st.Feeds[args[0]]; v.Settings == settings {
+			cached = v
+		}
+	}
+
+	b, fetched, err := fetchFeed(args[0], cached)
+	if err == errNotModified {
+		log.Printf("The %s feed has not changed since the last run (HTTP 304 Not Modified), nothing to do.", args[0])
+		return
 	}
 	if err != nil {
 		log.Fatal(err)
 	}
+	if settings != "" && !o.filtered() {
+		fetched.Settings = settings
+		o.feed, o.validators = args[0], fetched
+	}
+
+	var dir string
+	if len(args) == 2 {
+		dir = args[1]
+	}
+	if err := run(o, b, dir); err != nil {
+		fatal(err)
+	}
+}
+
+func fetchCmd(args []string) {
+	fs := flag.NewFlagSet("fetch", flag.ExitOnError)
+	out := fs.String("o", "", "write the feed to this file instead of stdout")
+	fs.Usage = func() {
+		log.Printf("Usage: %s fetch [options] <org/repo>", os.Args[0])
+		log.Println("options:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := setFromEnv(fs); err != nil {
+		log.Fatal(err)
+	}
 
-	if info == nil || !info.IsDir() {
-		log.Fatal("Second argument is not a directory.")
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
 	}
 
-	resp, err := http.Get("https://github.com/" + args[0] + "/releases.atom")
+	b, _, err := fetchFeed(fs.Arg(0), validators{})
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer resp.Body.Close()
-	b, err := ioutil.ReadAll(resp.Body)
 
+	if *out == "" {
+		_, err = os.Stdout.Write(b)
+	} else {
+		err = ioutil.WriteFile(*out, b, 0644)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
+}
 
-	exp := Export{}
+func convertCmd(args []string) {
+	o := &options{}
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	o.register(fs)
+	fs.Usage = func() {
+		log.Printf("Usage: %s convert [options] <feed.xml|-> <targetdir>", os.Args[0])
+		log.Printf("       %s convert [options] -archive <file>|-stdout <feed.xml|->", os.Args[0])
+		log.Println("options:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := setFromEnv(fs); err != nil {
+		log.Fatal(err)
+	}
+
+	if o.printConfig {
+		if err := printConfig(os.Stdout, fs); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if fs.NArg() != 2 && !(!o.needsDir() && fs.NArg() == 1) {
+		fs.Usage()
+		os.Exit(1)
+	}
 
-	err = xml.Unmarshal(b, &exp)
+	b, err := readInput(fs.Arg(0))
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	if len(exp.Entries) < 1 {
-		log.Fatal("No releases found!")
+	if err := run(o, b, fs.Arg(1)); err != nil {
+		fatal(err)
 	}
+}
 
-	count := 0
-	drafts := 0
-	for _, entry := range exp.Entries {
-		entry.Repo = strings.Replace(exp.Title, "Release notes from ", "", 1)
-		if len(exp.Title) > 0 {
-			entry.Description = fmt.Sprintf("%s: %s", exp.Title, entry.Title)
-		}
-		if extra != nil {
-			entry.Extra = *extra
+// readInput reads the feed in the file name, or on stdin when name is "-".
+func readInput(name string) ([]byte, error) {
+	if name == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(name)
+}
+
+// errNotModified is returned by fetchFeed when the feed has not changed
+// since it was fetched with the given validators.
+var errNotModified = errors.New("feed not modified")
+
+// fetchFeed downloads the releases feed of a Github org/repo. With cached
+// validators the request is conditional, returning errNotModified if the
+// feed did not change. It returns the validators of the fetched feed.
+func fetchFeed(repo string, cached validators) ([]byte, validators, error) {
+	req, err := http.NewRequest("GET", "https://github.com/"+repo+"/releases.atom", nil)
+	if err != nil {
+		return nil, cached, err
+	}
+	if cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+	if cached.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cached.LastModified)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, cached, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, cached, errNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, cached, fmt.Errorf("fetching %s releases: %s", repo, resp.Status)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	return b, validators{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}, err
+}
+
+// run converts the feed in b into posts in dir, or into the archive. Its
+// errors are for the caller to report; exitError asks for a given exit
+// status once the reason has been logged.
+func run(o *options, b []byte, dir string) (err error) {
+	// The first interrupt stops the run after the post being written, still
+	// saving the state and indexes of what was done. A second one kills it.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	defer func() {
+		switch {
+		case err != nil:
+		case ctx.Err() != nil:
+			err = exitError{130}
+		case o.failOnWarn && warnings > 0:
+			err = fmt.Errorf("Failing because of %d warnings (-fail-on-warn).", warnings)
 		}
+	}()
+
+	// The settings below are package state shared with the helpers, so
+	// every run starts them over.
+	warnings = 0
+	dateLayout = parseDateFormat(o.dateFormat)
+	preserveSlugCase = o.keepCase
+	idFilenames = o.idFilenames
+
+	if _, ok := dateFolderLayouts[o.dateFolders]; !ok && o.dateFolders != "" {
+		return fmt.Errorf("invalid -date-folders %q: use year, month or day", o.dateFolders)
+	}
+
+	switch o.trailingNL {
+	case "one", "none", "raw":
+		trailingNewline = o.trailingNL
+	default:
+		return fmt.Errorf("invalid -trailing-newline %q: use one, none or raw", o.trailingNL)
+	}
+
+	if o.inputFormat != "" && o.inputFormat != "atom" && o.inputFormat != "rss" && o.inputFormat != "json" {
+		return fmt.Errorf("invalid -input-format %q: use atom, rss or json", o.inputFormat)
+	}
 
-		if *convert {
-			entry.Content = html2md.Convert(entry.Content)
+	if o.perFile < 0 {
+		return errors.New("-entries-per-file must not be negative")
+	}
+
+	if !validSlugSeparator(o.slugSep) {
+		return fmt.Errorf("invalid -slug-separator %q: use -, _ or .", o.slugSep)
+	}
+	slugSeparator = o.slugSep
+
+	dateLocation = nil
+	if o.timezone != "" {
+		loc, err := time.LoadLocation(o.timezone)
+		if err != nil {
+			return fmt.Errorf("invalid -timezone %q: %w", o.timezone, err)
 		}
+		dateLocation = loc
+	}
 
-		// TODO count skips and writes separately
-		if err := writeEntry(entry, dir, *force); err != nil {
-			log.Fatalf("Failed writing post %q to disk:\n%s", entry.Title, err)
+	if o.lang != "" {
+		if _, err := language.Parse(o.lang); err != nil {
+			return fmt.Errorf("invalid -lang %q: %w", o.lang, err)
 		}
-		count++
 	}
-	log.Printf("Wrote %d published posts to disk.", count)
-	log.Printf("Wrote %d drafts to disk.", drafts)
-}
 
-func writeEntry(e Entry, dir string, overwrite bool) error {
-	filename := filepath.Join(dir, makePath(e.Title)+".md")
-	if _, err := os.Stat(filename); err == nil && !overwrite {
-		return nil
+	locale, err := parseLocale(o.locale)
+	if err != nil {
+		return fmt.Errorf("invalid -locale %q: %w", o.locale, err)
 	}
+	dateLocale = locale
 
-	f, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	match, err := compileFilter("match", o.match)
+	if err != nil {
+		return err
+	}
+	exclude, err := compileFilter("exclude", o.exclude)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
 
-	return t.Execute(f, e)
-}
+	if o.words && o.wpm < 1 {
+		return errors.New("-wpm must be at least 1")
+	}
 
-// Take a string with any characters and replace it so the string could be used in a path.
-// E.g. Social Media -> social-media
-func makePath(s string) string {
-	return unicodeSanitize(strings.ToLower(strings.Replace(strings.TrimSpace(s), " ", "-", -1)))
+	var strip []selector
+	for _, spec := range o.strip {
+		sels, err := parseSelectors(spec)
+		if err != nil {
+			return fmt.Errorf("invalid -strip-selector: %w", err)
+		}
+		strip = append(strip, sels...)
+	}
+
+	repoMap := make(map[string]string)
+	for _, spec := range o.repoMap {
+		i := strings.Index(spec, "=")
+		if i <= 0 || i == len(spec)-1 {
+			return fmt.Errorf("invalid -repo-map %q: expected old=new", spec)
+		}
+		repoMap[spec[:i]] = spec[i+1:]
+	}
+
+	var allow policy
+	if o.sanitize {
+		if allow, err = newPolicy(o.sanitizeAllow); err != nil {
+			return err
+		}
+	} else if len(o.sanitizeAllow) > 0 {
+		return errors.New("-sanitize-allow requires -sanitize")
+	}
+
+	var scrubs []*regexp.Regexp
+	if o.scrub {
+		if scrubs, err = compileScrub(o.scrubPatterns); err != nil {
+			return err
+		}
+	} else if len(o.scrubPatterns) > 0 {
+		return errors.New("-scrub-pattern requires -scrub")
+	}
+
+	var descTmpl *template.Template
+	if o.relDesc {
+		if o.descTemplate != "" {
+			return errors.New("-relative-description and -description-template cannot be used together, use reltime in the template instead")
+		}
+		o.descTemplate = relativeDescription
+	}
+	if o.descTemplate != "" {
+		if descTmpl, err = template.New("description").Funcs(funcMap).Parse(o.descTemplate); err != nil {
+			return fmt.Errorf("invalid -description-template: %w", err)
+		}
+	}
+
+	var linkTmpl *template.Template
+	if o.relativeLinks != "" {
+		if linkTmpl, err = template.New("links").Funcs(funcMap).Parse(o.relativeLinks); err != nil {
+			return fmt.Errorf("invalid -relative-links: %w", err)
+		}
+	}
+
+	var tagTmpl *template.Template
+	if o.tagTemplate != "" {
+		if tagTmpl, err = template.New("tags").Funcs(funcMap).Parse(o.tagTemplate); err != nil {
+			return fmt.Errorf("invalid -tag-template: %w", err)
+		}
+	}
+
+	var prependTmpl, appendTmpl *template.Template
+	if o.prependFile != "" {
+		if prependTmpl, err = loadTemplate(o.prependFile); err != nil {
+			return fmt.Errorf("invalid -prepend-file: %w", err)
+		}
+	}
+	if o.appendFile != "" {
+		if appendTmpl, err = loadTemplate(o.appendFile); err != nil {
+			return fmt.Errorf("invalid -append-file: %w", err)
+		}
+	}
+
+	var switches []string
+	if o.setTOC {
+		switches = append(switches, "toc: true")
+	}
+	if o.setMath {
+		switches = append(switches, "math: true")
+	}
+	schema, err := newExtraSchema(o.extraKeys)
+	if err != nil {
+		return err
+	}
+	extra, err := schema.loadExtra(o.extra, o.extraFile, switches...)
+	if err != nil {
+		return fmt.Errorf("invalid extra frontmatter: %w", err)
+	}
+
+	if keyMap, err = parseKeyMap(o.keyMap); err != nil {
+		return err
+	}
+	for _, k := range frontmatterKeys(extra) {
+		for from, to := range keyMap {
+			if k.name == to {
+				return fmt.Errorf("invalid extra frontmatter: key %q is already set by releasetoblog, renamed from %q", to, from)
+			}
+		}
+	}
+
+	var slugs map[string]string
+	if o.slugMap != "" {
+		if slugs, err = loadSlugMap(o.slugMap); err != nil {
+			return fmt.Errorf("invalid -slug-map: %w", err)
+		}
+	}
+
+	if o.stripFullLog && !o.fullLog {
+		return errors.New("-strip-full-changelog requires -full-changelog")
+	}
+	if o.defaultURI != "" && o.defaultAuthor == "" {
+		return errors.New("-default-author-uri requires -default-author")
+	}
+	if o.merge && o.force {
+		return errors.New("-merge-frontmatter and -force cannot be used together")
+	}
+	if o.check && (o.diff || o.stdout || o.archive != "") {
+		return errors.New("-check cannot be used with -diff, -stdout or -archive")
+	}
+
+	if o.prune != "" && o.stateFile == "" {
+		return errors.New("-prune requires -state")
+	}
+	if o.maxAge > 0 && o.stateFile == "" {
+		return errors.New("-max-age requires -state")
+	}
+
+	if !validOrder(o.sort) {
+		return fmt.Errorf("invalid -sort %q: use asc or desc", o.sort)
+	}
+	if !validOrder(o.indexSort) {
+		return fmt.Errorf("invalid -index-sort %q: use asc or desc", o.indexSort)
+	}
+
+	if o.maxBodyLen > 0 && !o.convert {
+		return errors.New("-max-body-len requires -convert")
+	}
+
+	if o.dateSource != "updated" && o.dateSource != "published" {
+		return fmt.Errorf("invalid -date-source %q: use updated or published", o.dateSource)
+	}
+
+	if o.keepHTML != "" && o.keepHTML != "frontmatter" && o.keepHTML != "sidecar" {
+		return fmt.Errorf("invalid -keep-html %q: use frontmatter or sidecar", o.keepHTML)
+	}
+
+	var outputs []output
+	if dir != "" || len(o.outputs) == 0 {
+		primary := output{dir: dir, tmpl: t}
+		if o.template != "" {
+			if primary.tmpl, err = loadTemplate(o.template); err != nil {
+				return fmt.Errorf("Failed loading template %q:\n%w", o.template, err)
+			}
+		}
+		for _, spec := range o.templateRules {
+			rule, err := parseTemplateRule(spec)
+			if err != nil {
+				return err
+			}
+			primary.rules = append(primary.rules, rule)
+		}
+		primary.prereleaseDir = o.prereleaseDir
+		outputs = append(outputs, primary)
+	} else if o.prereleaseDir != "" {
+		return errors.New("-prerelease-dir requires a target directory")
+	}
+	// git only sees the files on disk.
+	if _, disk := destination.(osFS); o.gitCommit && !disk {
+		return errors.New("-git-commit requires writing the posts to disk")
+	}
+	for _, spec := range o.outputs {
+		out, err := parseOutput(spec)
+		if err != nil {
+			return err
+		}
+		outputs = append(outputs, out)
+	}
+	for i := range outputs {
+		outputs[i].dateFolders = o.dateFolders
+		outputs[i].lang = o.lang
+	}
+
+	if o.archive == "" && !o.stdout && !o.diff && !o.check && !o.list && !o.collisions {
+		var dirs []string
+		for _, out := range outputs {
+			for _, dest := range out.destinations() {
+				dirs = append(dirs, dest.dir)
+			}
+		}
+		for _, dir := range dirs {
+			if err := prepareDir(dir); err != nil {
+				return err
+			}
+
+			if o.yes || o.force {
+				continue
+			}
+			n, err := countForeignFiles(dir)
+			if err != nil {
+				return err
+			}
+			if n > o.maxForeign {
+				return fmt.Errorf("%s holds %d files that are not posts, is it the right directory? Pass -yes to write there anyway.", dir, n)
+			}
+		}
+	}
+
+	var exp Export
+	if o.inputFormat == "json" || (o.inputFormat == "" && isJSON(b)) {
+		exp, err = parseReleases(b)
+	} else if o.inputFormat == "rss" || (o.inputFormat == "" && isRSS(b)) {
+		exp, err = parseRSS(b)
+	} else {
+		exp, err = parseFeed(b)
+	}
+	if err != nil {
+		return feedError{err}
+	}
+
+	if len(exp.Entries) < 1 {
+		return ErrNoEntries
+	}
+
+	var st *state
+	if o.stateFile != "" && o.archive == "" && !o.stdout && !o.diff && !o.check && !o.list && !o.collisions {
+		if st, err = loadState(o.stateFile); err != nil {
+			return fmt.Errorf("Failed reading state file %q:\n%w", o.stateFile, err)
+		}
+	}
+
+	var arc *archiveWriter
+	if o.archive != "" {
+		if arc, err = newArchiveWriter(o.archive, dir); err != nil {
+			return err
+		}
+	}
+
+	repo := trimReleasePrefix(exp.Title, o.trimPrefixes)
+	if o.repo != "" {
+		repo = o.repo
+	}
+	if renamed, ok := repoMap[repo]; ok {
+		repo = renamed
+	}
+
+	var entries []Entry
+	filtered := 0
+	byAuthor := 0
+	tooOld := 0
+	cutoff := clock().Add(-time.Duration(o.maxAge))
+	for _, entry := range exp.Entries {
+		if (match != nil && !match.MatchString(entry.Title)) || (exclude != nil && exclude.MatchString(entry.Title)) {
+			filtered++
+			continue
+		}
+		if (len(o.onlyAuthors) > 0 && !authorMatches(entry.Author, o.onlyAuthors)) || authorMatches(entry.Author, o.exclAuthors) {
+			byAuthor++
+			continue
+		}
+		if o.limit > 0 && len(entries) == o.limit {
+			break
+		}
+		entry.Date = entry.Updated
+		if o.dateSource == "published" && !entry.Published.IsZero() {
+			entry.Date = entry.Published
+		}
+		if o.maxAge > 0 && time.Time(entry.Date).Before(cutoff) {
+			tooOld++
+			continue
+		}
+		if slug, ok := mappedSlug(slugs, entry); ok {
+			entry.slug = slug
+			log.Printf("Using slug %q from -slug-map for %q.", slug, entry.Title)
+		}
+		entry.Repo = repo
+		if o.fetchBodies && strings.TrimSpace(entry.Content) == "" {
+			backfillContent(ctx, &entry, o.token)
+		}
+		if allow != nil {
+			var n int
+			if entry.markdown {
+				entry.Content, n = allow.sanitizeMarkdown(entry.Content)
+			} else if entry.Content, n, err = allow.sanitize(entry.Content); err != nil {
+				return fmt.Errorf("Failed sanitizing html of %q:\n%w", entry.Title, err)
+			}
+			if n > 0 {
+				log.Printf("Sanitized %d elements and attributes of %q.", n, entry.Title)
+			}
+		}
+		if len(strip) > 0 && !entry.markdown {
+			if entry.Content, err = stripElements(entry.Content, strip); err != nil {
+				return fmt.Errorf("Failed stripping html from %q:\n%w", entry.Title, err)
+			}
+		}
+		if linkTmpl != nil && !entry.markdown {
+			repo, _, _ := releaseTag(entry.Links.Alternate().Href)
+			if entry.Content, _, err = rewriteIssueLinks(entry.Content, linkTmpl, repo); err != nil {
+				return fmt.Errorf("Failed rewriting links of %q:\n%w", entry.Title, err)
+			}
+		}
+		if o.fullLog {
+			if entry.FullChangelog, entry.Content, err = fullChangelog(entry.Content, entry.markdown, o.stripFullLog); err != nil {
+				return fmt.Errorf("Failed finding the full changelog link of %q:\n%w", entry.Title, err)
+			}
+		}
+		if o.emojify {
+			if entry.Content, err = emojify(entry.Content, entry.markdown); err != nil {
+				return fmt.Errorf("Failed replacing emoji shortcodes in %q:\n%w", entry.Title, err)
+			}
+		}
+		if o.groupConv {
+			if entry.Content, err = groupConventional(entry.Content, entry.markdown); err != nil {
+				return fmt.Errorf("Failed grouping the changes of %q:\n%w", entry.Title, err)
+			}
+		}
+		if o.autoMore {
+			if entry.Content, err = insertMore(entry.Content, entry.markdown); err != nil {
+				return fmt.Errorf("Failed inserting summary divider in %q:\n%w", entry.Title, err)
+			}
+		}
+		if scrubs != nil {
+			var n int
+			if entry.Content, n = scrub(entry.Content, scrubs); n > 0 {
+				log.Printf("Redacted %d matches from %q.", n, entry.Title)
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return dateLess(entries[i].Date, entries[j].Date, o.sort)
+	})
+
+	if o.collapse {
+		var merged int
+		entries, merged = collapsePatches(entries, o.collapseWindow)
+		if merged > 0 {
+			log.Printf("Merged %d patch releases into the posts of their newest patch.", merged)
+		}
+	}
+
+	if o.perFile > 0 {
+		entries = digestEntries(entries, o.perFile)
+	}
+
+	if o.list {
+		if err := listEntries(os.Stdout, entries, o.noVersion || o.perFile > 0); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	if o.collisions {
+		collisions := findCollisions(outputs, entries)
+		if err := reportCollisions(os.Stdout, collisions); err != nil {
+			return err
+		}
+		if len(collisions) > 0 {
+			log.Printf("Found %d files that several releases would be written to; only the first is written, or the last with -force.", len(collisions))
+			return exitError{1}
+		}
+		log.Printf("No collisions among the posts of %d releases.", len(entries))
+		return nil
+	}
+
+	// Tag-only releases have no notes, so skip entries whose body is empty.
+	empty := 0
+	if !o.includeEmpty {
+		kept := entries[:0]
+		for _, entry := range entries {
+			if emptyBody(entry.Content, entry.markdown) {
+				empty++
+				continue
+			}
+			kept = append(kept, entry)
+		}
+		entries = kept
+	}
+
+	var markdown []string
+	if o.convert || o.words || o.mentions || o.singleDoc != "" {
+		prog := newProgress("Converting", len(entries), o.quiet)
+		markdown = convertContents(entries, prog)
+		prog.finish()
+	}
+
+	var compare map[string]string
+	if o.compareURL {
+		compare = compareURLs(entries)
+	}
+
+	count := 0
+	drafts := 0
+	existing := 0
+	truncated := 0
+	defaultAuthors := 0
+	perDir := make(map[string]int)
+	indexPosts := make(map[string][]indexPost)
+	var changed, changedTitles, stale []string
+	var changelogSections []changelogSection
+	var summaryPosts []summaryPost
+	var docPosts []Entry
+	// Posts printed to stdout would be mixed with the progress bar.
+	prog := newProgress("Writing", len(entries), o.quiet || o.stdout || o.diff)
+	for i, entry := range entries {
+		prog.update(i)
+		if ctx.Err() != nil {
+			prog.finish()
+			log.Printf("Interrupted, stopping after %d of %d releases.", i, len(entries))
+			break
+		}
+
+		entry.RepoPrefix = o.repoPrefix
+		entry.Changelog = entry.changelogs()
+		if entry.Repo != "" && o.series {
+			entry.Series = []string{entry.QualifiedRepo()}
+		}
+		if strings.TrimSpace(entry.Title) == "" {
+			entry.Title = synthesizeTitle(entry)
+			warnf("Entry %q has no title, using %q.", entry.ID, entry.Title)
+		}
+		entry.Version = entry.Title
+		entry.Prerelease = entry.Prerelease || isPrerelease(entry.Version)
+		if o.noVersion || o.perFile > 0 {
+			entry.Version = ""
+		}
+		if o.futureDrafts && time.Time(entry.Date).After(clock()) {
+			publish := entry.Date
+			entry.PublishDate = &publish
+			entry.Draft = true
+		}
+		if entry.Prerelease && o.expiryDays > 0 {
+			expiry := Date(time.Time(entry.Date).AddDate(0, 0, o.expiryDays))
+			entry.ExpiryDate = &expiry
+		}
+		if o.titleDate {
+			entry.TitleDate = o.titleDateSep + yearMonthDate(entry.Date)
+		}
+		entry.FeedTitle = exp.Title
+		if len(exp.Title) > 0 {
+			entry.Description = fmt.Sprintf("%s: %s", exp.Title, entry.Title)
+		}
+		entry.Extra = extra
+		entry.Lang = o.lang
+		entry.SEO = o.seo
+		entry.CompareURL = compare[entry.ID]
+		if o.canonical {
+			entry.CanonicalURL = entry.Links.Alternate().Href
+		}
+		entry.Assets = assets(entry.Links)
+		if o.cover {
+			entry.Cover = firstImage(entry.Content, entry.markdown)
+		}
+		if o.defaultAuthor != "" && strings.TrimSpace(entry.Author.Name) == "" {
+			entry.Author = Author{Name: o.defaultAuthor, Uri: o.defaultURI}
+			defaultAuthors++
+		}
+		if o.avatar {
+			entry.Author.Avatar = githubAvatar(entry.Author.Uri)
+		}
+		if o.stamp {
+			entry.Stamp = generatedStamp(&exp, entry)
+		}
+
+		if o.changelogFile != "" {
+			changelogSections = append(changelogSections, newChangelogSection(entry, entry.Content))
+		}
+		var summary string
+		if o.summaryFile != "" {
+			summary = summaryLine(entry.Content, entry.markdown)
+		}
+
+		var rawHTML string
+		switch o.keepHTML {
+		case "frontmatter":
+			entry.ContentHTML = strings.TrimSpace(entry.Content)
+		case "sidecar":
+			rawHTML = entry.Content
+		}
+
+		if o.convert {
+			entry.Content = markdown[i]
+		}
+
+		if o.words {
+			entry.Words, entry.ReadingTime = readingTime(markdown[i], o.wpm)
+		}
+
+		if o.mentions {
+			entry.Contributors, entry.References = mentions(markdown[i])
+		}
+
+		if descTmpl != nil {
+			if entry.Description, err = renderDescription(descTmpl, entry); err != nil {
+				return fmt.Errorf("Failed rendering description of %q:\n%w", entry.Title, err)
+			}
+		}
+		if tagTmpl != nil {
+			if entry.Tags, err = renderTags(tagTmpl, entry); err != nil {
+				return fmt.Errorf("Failed rendering tags of %q:\n%w", entry.Title, err)
+			}
+		}
+
+		if o.summaryFile != "" {
+			summaryPosts = append(summaryPosts, summaryPost{Entry: entry, Summary: summary})
+		}
+		if o.singleDoc != "" {
+			post := entry
+			post.Content = demoteHeadings(markdown[i])
+			docPosts = append(docPosts, post)
+		}
+
+		if o.maxBodyLen > 0 {
+			var cut bool
+			if entry.Content, cut = truncateBody(entry.Content, o.maxBodyLen, entry.Links.Alternate().Href); cut {
+				truncated++
+			}
+		}
+
+		if prependTmpl != nil || appendTmpl != nil {
+			if entry.Content, err = wrapBody(prependTmpl, appendTmpl, entry); err != nil {
+				return fmt.Errorf("Failed rendering the -prepend-file or -append-file of %q:\n%w", entry.Title, err)
+			}
+		}
+
+		for _, out := range outputs {
+			out = out.forEntry(entry)
+			if o.index {
+				addIndexPost(indexPosts, out, entry)
+			}
+
+			if o.validate {
+				if fm, err := validateEntry(out, entry); err != nil {
+					warnf("Post %q has invalid frontmatter: %s\n%s", entry.Title, err, fm)
+				}
+			}
+
+			if o.diff {
+				changed, err := diffEntry(out, entry)
+				if err != nil {
+					return fmt.Errorf("Failed comparing post %q:\n%w", entry.Title, err)
+				}
+				if changed {
+					count++
+				}
+				continue
+			}
+
+			if o.check {
+				upToDate, err := checkEntry(out, entry)
+				if err != nil {
+					return fmt.Errorf("Failed comparing post %q:\n%w", entry.Title, err)
+				}
+				if !upToDate {
+					stale = append(stale, out.path(entry, ".md"))
+				}
+				continue
+			}
+
+			if o.stdout {
+				if err := printEntry(out, entry); err != nil {
+					return fmt.Errorf("Failed printing post %q:\n%w", entry.Title, err)
+				}
+				count++
+				continue
+			}
+
+			if arc != nil {
+				if err := archiveEntry(arc, out, entry); err != nil {
+					return fmt.Errorf("Failed adding post %q to archive:\n%w", entry.Title, err)
+				}
+				if rawHTML != "" {
+					if err := arc.AddFile(out.path(entry, ".html"), []byte(rawHTML)); err != nil {
+						return fmt.Errorf("Failed adding html for %q to archive:\n%w", entry.Title, err)
+					}
+				}
+				if o.sidecarJSON {
+					b, err := sidecarJSON(entry)
+					if err == nil {
+						err = arc.AddFile(out.path(entry, ".json"), b)
+					}
+					if err != nil {
+						return fmt.Errorf("Failed adding metadata for %q to archive:\n%w", entry.Title, err)
+					}
+				}
+				count++
+				continue
+			}
+
+			var written bool
+			if o.merge {
+				written, err = mergeEntry(out, entry)
+			} else {
+				written, err = writeEntry(out, entry, o.force)
+			}
+			if err != nil {
+				return &ErrWrite{Path: out.path(entry, ".md"), what: fmt.Sprintf("post %q to disk", entry.Title), Err: err}
+			}
+			if st != nil {
+				st.record(entry.ID, out.path(entry, ".md"))
+			}
+			if written {
+				changed = append(changed, out.path(entry, ".md"))
+				changedTitles = append(changedTitles, entry.Title)
+			}
+			if rawHTML != "" {
+				if _, err := writeFile(out.path(entry, ".html"), []byte(rawHTML), o.force); err != nil {
+					return &ErrWrite{Path: out.path(entry, ".html"), what: fmt.Sprintf("html for %q to disk", entry.Title), Err: err}
+				}
+				changed = append(changed, out.path(entry, ".html"))
+				if st != nil {
+					st.record(entry.ID, out.path(entry, ".html"))
+				}
+			}
+			if o.sidecarJSON {
+				b, err := sidecarJSON(entry)
+				if err == nil {
+					_, err = writeFile(out.path(entry, ".json"), b, o.force)
+				}
+				if err != nil {
+					return &ErrWrite{Path: out.path(entry, ".json"), what: fmt.Sprintf("metadata for %q to disk", entry.Title), Err: err}
+				}
+				changed = append(changed, out.path(entry, ".json"))
+				if st != nil {
+					st.record(entry.ID, out.path(entry, ".json"))
+				}
+			}
+			if !written {
+				existing++
+				continue
+			}
+			perDir[out.dir]++
+			if entry.Draft {
+				drafts++
+				continue
+			}
+			count++
+		}
+	}
+
+	prog.finish()
+
+	if filtered > 0 {
+		log.Printf("Skipped %d entries excluded by -match/-exclude.", filtered)
+	}
+	if byAuthor > 0 {
+		log.Printf("Skipped %d entries excluded by -only-authors/-exclude-authors.", byAuthor)
+	}
+	if tooOld > 0 {
+		log.Printf("Skipped %d entries older than -max-age.", tooOld)
+	}
+	if empty > 0 {
+		log.Printf("Skipped %d releases with empty notes, use -include-empty-bodies to write them.", empty)
+	}
+	if defaultAuthors > 0 {
+		log.Printf("Used the -default-author for %d releases without an author.", defaultAuthors)
+	}
+	if truncated > 0 {
+		log.Printf("Truncated %d posts longer than %d characters.", truncated, o.maxBodyLen)
+	}
+
+	if o.diff {
+		log.Printf("%d posts would change.", count)
+		return nil
+	}
+
+	if o.check {
+		if len(stale) > 0 {
+			log.Printf("%d posts are missing or out of date:", len(stale))
+			for _, filename := range stale {
+				log.Printf("  %s", filename)
+			}
+			return exitError{1}
+		}
+		log.Println("All posts are up to date.")
+		return nil
+	}
+
+	if o.stdout {
+		log.Printf("Printed %d posts.", count)
+		return nil
+	}
+
+	if st != nil {
+		if o.prune != "" {
+			ids := make(map[string]bool)
+			for _, entry := range exp.Entries {
+				ids[entry.ID] = true
+			}
+			pruned, files, err := prune(st, ids, o.prune == "soft")
+			if err != nil {
+				return err
+			}
+			changed = append(changed, files...)
+			log.Printf("Pruned %d posts of releases no longer in the feed.", pruned)
+		}
+		if o.maxAge > 0 && ctx.Err() == nil {
+			expired, files, err := expire(st, cutoff)
+			if err != nil {
+				return err
+			}
+			changed = append(changed, files...)
+			if expired > 0 {
+				log.Printf("Removed the posts of %d releases older than -max-age.", expired)
+			}
+		}
+		if o.feed != "" && ctx.Err() == nil {
+			if st.Feeds == nil {
+				st.Feeds = make(map[string]validators)
+			}
+			st.Feeds[o.feed] = o.validators
+		}
+		if err := st.save(o.stateFile); err != nil {
+			return &ErrWrite{Path: o.stateFile, what: fmt.Sprintf("state file %q", o.stateFile), Err: err}
+		}
+	}
+
+	if o.index {
+		indexSort := o.indexSort
+		if indexSort == "" {
+			indexSort = o.sort
+		}
+		for _, out := range outputs {
+			for _, dest := range out.destinations() {
+				b, err := renderIndex(exp, indexPosts[dest.dir], indexSort)
+				if err == nil {
+					if arc != nil {
+						err = arc.AddFile(indexPath(dest), b)
+					} else {
+						_, err = writeFile(indexPath(dest), b, true)
+						changed = append(changed, indexPath(dest))
+					}
+				}
+				if err != nil {
+					return &ErrWrite{Path: indexPath(dest), what: fmt.Sprintf("index for %q", dest.dir), Err: err}
+				}
+			}
+		}
+	}
+
+	if arc != nil {
+		if err := arc.Close(); err != nil {
+			return &ErrWrite{Path: o.archive, what: fmt.Sprintf("archive %q", o.archive), Err: err}
+		}
+		log.Printf("Wrote %d published posts to %s.", count, o.archive)
+		return nil
+	}
+
+	if o.changelogFile != "" {
+		added, err := updateChangelog(o.changelogFile, changelogSections)
+		if err != nil {
+			return fmt.Errorf("Failed updating %s:\n%w", o.changelogFile, err)
+		}
+		if added > 0 {
+			changed = append(changed, o.changelogFile)
+		}
+		log.Printf("Added %d releases to %s.", added, o.changelogFile)
+	}
+
+	if o.summaryFile != "" {
+		b, err := renderSummary(exp.Title, summaryPosts)
+		if err == nil {
+			_, err = writeFile(o.summaryFile, b, true)
+		}
+		if err != nil {
+			return &ErrWrite{Path: o.summaryFile, what: "summary " + o.summaryFile, Err: err}
+		}
+		changed = append(changed, o.summaryFile)
+		log.Printf("Summarized %d releases in %s.", len(summaryPosts), o.summaryFile)
+	}
+
+	if o.singleDoc != "" {
+		b, err := renderSingleDoc(exp, docPosts)
+		if err == nil {
+			_, err = writeFile(o.singleDoc, b, true)
+		}
+		if err != nil {
+			return &ErrWrite{Path: o.singleDoc, what: o.singleDoc, Err: err}
+		}
+		changed = append(changed, o.singleDoc)
+		log.Printf("Wrote %d releases to %s.", len(docPosts), o.singleDoc)
+	}
+
+	if o.gitCommit {
+		committed, err := gitCommit(outputs[0].dir, changed, commitMessage(repo, changedTitles), o.gitAuthor)
+		if err != nil {
+			return fmt.Errorf("Failed committing posts:\n%w", err)
+		}
+		if committed {
+			log.Printf("Committed %d changed files.", len(changed))
+		} else {
+			log.Println("Nothing changed, skipped the git commit.")
+		}
+	}
+
+	if o.force {
+		log.Println("Ran in -force mode, existing posts were overwritten.")
+	} else if existing > 0 && o.merge {
+		log.Printf("Left %d posts that are already up to date.", existing)
+	} else if existing > 0 {
+		log.Printf("Skipped %d posts that already exist, use -force to overwrite them.", existing)
+	}
+	log.Printf("Wrote %d published posts to disk.", count)
+	if drafts > 0 {
+		log.Printf("Wrote %d drafts to disk.", drafts)
+	}
+	if o.prereleaseDir != "" {
+		for _, out := range outputs {
+			for _, dest := range out.destinations() {
+				log.Printf("Wrote %d posts to %s.", perDir[dest.dir], dest.dir)
+			}
+		}
+	}
+	return nil
+}
+
+// defaultReleasePrefix starts the title of Github's release feeds.
+const defaultReleasePrefix = "Release notes from "
+
+// trimReleasePrefix derives the repo name from a feed title by removing the
+// first matching prefix, or defaultReleasePrefix when none are given.
+func trimReleasePrefix(title string, prefixes []string) string {
+	if len(prefixes) == 0 {
+		prefixes = []string{defaultReleasePrefix}
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(title, prefix) {
+			return strings.TrimPrefix(title, prefix)
+		}
+	}
+	return title
+}
+
+// warnings counts the problems reported with warnf.
+var warnings int
+
+// warnf logs a problem that did not stop the run, such as an entry that had
+// to be completed by guessing. With -fail-on-warn they fail the run at the end.
+func warnf(format string, v ...interface{}) {
+	warnings++
+	log.Printf("warning: "+format, v...)
+}
+
+// backfillContent fills the empty content of an entry with the release notes
+// from the Github API. Failures are logged and leave the entry unchanged.
+func backfillContent(ctx context.Context, e *Entry, token string) {
+	repo, tag, ok := releaseTag(e.Links.Alternate().Href)
+	if !ok {
+		warnf("Entry %q has no body and no Github release link to fetch it from.", e.Title)
+		return
+	}
+
+	body, err := fetchReleaseHTML(ctx, repo, tag, token)
+	if err != nil {
+		warnf("Failed fetching the body of %q: %s", e.Title, err)
+		return
+	}
+	if strings.TrimSpace(body) == "" {
+		return
+	}
+
+	// The body is html even for entries of a feed with markdown bodies.
+	e.Content, e.markdown = body, false
+	log.Printf("Fetched the missing body of %q from the Github API.", e.Title)
+}
+
+// prune removes the files of entries in the state that are not in ids, or
+// with soft set marks the posts among them as drafts. It returns the number
+// of entries pruned and the files removed or changed.
+func prune(st *state, ids map[string]bool, soft bool) (int, []string, error) {
+	var files []string
+
+	// A release whose ID changed may still be written to the same file.
+	current := make(map[string]bool)
+	for id := range ids {
+		for _, filename := range st.Posts[id] {
+			current[filename] = true
+		}
+	}
+
+	missing := st.missing(ids)
+	for _, id := range missing {
+		for _, filename := range st.Posts[id] {
+			if current[filename] {
+				continue
+			}
+			if soft {
+				if filepath.Ext(filename) != ".md" {
+					continue
+				}
+				changed, err := markDraft(filename)
+				if err != nil && !os.IsNotExist(err) {
+					return 0, files, fmt.Errorf("Failed marking %s as draft:\n%w", filename, err)
+				}
+				if changed {
+					files = append(files, filename)
+					log.Printf("Marked %s as draft, release %s is no longer in the feed.", filename, id)
+				}
+				continue
+			}
+
+			err := destination.Remove(filename)
+			if err != nil && !os.IsNotExist(err) {
+				return 0, files, fmt.Errorf("Failed removing %s:\n%w", filename, err)
+			}
+			if err == nil {
+				files = append(files, filename)
+				log.Printf("Removed %s, release %s is no longer in the feed.", filename, id)
+			}
+		}
+		if !soft {
+			delete(st.Posts, id)
+		}
+	}
+	return len(missing), files, nil
+}
+
+// prepareDir creates dir if needed and checks that posts can be written to
+// it.
+func prepareDir(dir string) error {
+	info, err := destination.Stat(dir)
+	if os.IsNotExist(err) {
+		if err = destination.MkdirAll(dir); err == nil {
+			info, err = destination.Stat(dir)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("%s is a file, not a directory. The target directory is where posts are written:\n  %s [options] <org/repo> <targetdir>", dir, os.Args[0])
+	}
+
+	probe := filepath.Join(dir, fmt.Sprintf(".releasetoblog-%d", os.Getpid()))
+	f, err := destination.Create(probe)
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %s", dir, err)
+	}
+	return destination.Remove(probe)
+}
+
+// countForeignFiles counts the entries of dir other than the .md and .html
+// files releasetoblog writes.
+func countForeignFiles(dir string) (int, error) {
+	infos, err := destination.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, info := range infos {
+		ext := strings.ToLower(filepath.Ext(info.Name()))
+		if info.IsDir() || (ext != ".md" && ext != ".html") {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// noteElements are the elements that make an html body worth a post without
+// any text, as they convert to markdown of their own.
+var noteElements = map[string]bool{"img": true, "hr": true, "input": true}
+
+// emptyBody reports whether a release body holds no notes: it is white space,
+// or html without text or noteElements. It finds out without converting the
+// body, which most runs would only do for this.
+func emptyBody(body string, markdown bool) bool {
+	if markdown || strings.TrimSpace(body) == "" {
+		return strings.TrimSpace(body) == ""
+	}
+	z := html.NewTokenizer(strings.NewReader(body))
+	hidden := false // in a script or style, which are not converted
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return true
+		case html.TextToken:
+			if !hidden && strings.TrimSpace(html.UnescapeString(string(z.Text()))) != "" {
+				return false
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			if noteElements[string(name)] {
+				return false
+			}
+			hidden = string(name) == "script" || string(name) == "style"
+		case html.EndTagToken:
+			hidden = false
+		}
+	}
+}
+
+// convertContents converts the html content of each entry to markdown,
+// reporting to prog. html2md compiles its regexps on every call, which makes
+// conversion by far the slowest step for large feeds, so the work is spread
+// over all CPUs.
+func convertContents(entries []Entry, prog *progress) []string {
+	out := make([]string, len(entries))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if entries[i].markdown {
+					out[i] = entries[i].Content
+				} else {
+					out[i] = convertBody(entries[i].Content)
+				}
+			}
+		}()
+	}
+
+	for i := range entries {
+		jobs <- i
+		prog.update(i)
+	}
+	close(jobs)
+	wg.Wait()
+
+	return out
+}
+
+// parseFeed decodes an Atom feed.
+func parseFeed(b []byte) (Export, error) {
+	exp := Export{}
+	err := decodeFeed(b, &exp)
+	return exp, err
+}
+
+// decodeFeed decodes the XML feed in b into v. Errors report the byte offset
+// where decoding stopped, along with the input surrounding it.
+func decodeFeed(b []byte, v interface{}) error {
+	// The decoder accepts a UTF-8 byte order mark, but feeds saved as UTF-16
+	// by Windows tools have to be converted first.
+	if u, ok := decodeUTF16(b); ok {
+		b = u
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	dec.CharsetReader = feedCharsetReader
+	if err := dec.Decode(v); err != nil {
+		offset := dec.InputOffset()
+		return fmt.Errorf("invalid feed at byte offset %d: %s\nnear: %q", offset, err, snippet(b, offset, 40))
+	}
+	return nil
+}
+
+// feedCharsetReader accepts the UTF-16 declaration of feeds converted by
+// decodeUTF16, and rejects any other encoding than UTF-8.
+func feedCharsetReader(charset string, r io.Reader) (io.Reader, error) {
+	if strings.EqualFold(charset, "utf-16") {
+		return r, nil
+	}
+	return nil, fmt.Errorf("unsupported feed encoding %q", charset)
+}
+
+// decodeUTF16 converts UTF-16 text starting with a byte order mark to UTF-8.
+// It reports false for any other input.
+func decodeUTF16(b []byte) ([]byte, bool) {
+	var order binary.ByteOrder
+	switch {
+	case bytes.HasPrefix(b, []byte{0xff, 0xfe}):
+		order = binary.LittleEndian
+	case bytes.HasPrefix(b, []byte{0xfe, 0xff}):
+		order = binary.BigEndian
+	default:
+		return nil, false
+	}
+
+	units := make([]uint16, (len(b)-2)/2)
+	for i := range units {
+		units[i] = order.Uint16(b[2+2*i:])
+	}
+	return []byte(string(utf16.Decode(units))), true
+}
+
+// snippet returns up to n bytes of b on either side of offset.
+func snippet(b []byte, offset int64, n int64) string {
+	start, end := offset-n, offset+n
+	if start < 0 {
+		start = 0
+	}
+	if end > int64(len(b)) {
+		end = int64(len(b))
+	}
+	if start > end {
+		start = end
+	}
+	return string(b[start:end])
+}
+
+var prereleaseRe = regexp.MustCompile(`(?i)(^|[^a-z])(alpha|beta|rc|pre|preview|dev|nightly|snapshot|canary)([^a-z]|$)|^v?\d+\.\d+\.\d+-`)
+
+// isPrerelease reports whether a version looks like a pre-release, such as
+// v1.2.0-rc.1 or 2.0 beta.
+func isPrerelease(version string) bool {
+	return prereleaseRe.MatchString(version)
+}
+
+// synthesizeTitle makes up a title for an untitled entry from the release
+// tag in its ID, or else from the repo and date.
+func synthesizeTitle(e Entry) string {
+	// Github entry IDs end with the tag, e.g.
+	// tag:github.com,2008:Repository/123/v1.2.0
+	if i := strings.LastIndex(e.ID, "/"); i >= 0 && i < len(e.ID)-1 {
+		return e.ID[i+1:]
+	}
+	if e.Repo != "" {
+		return e.Repo + " " + yearMonthDate(e.Date)
+	}
+	return yearMonthDate(e.Date)
+}
+
+// yamlEscaper escapes a string for a double quoted YAML value. The built-in
+// templates apply it, as the yaml function, to every quoted value.
+var yamlEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// renderDescription executes a -description-template for e. The result is
+// collapsed onto one line.
+func renderDescription(tmpl *template.Template, e Entry) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, e); err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(buf.String()), " "), nil
+}
+
+// renderTags executes a -tag-template for e and splits the result on commas
+// and newlines into a list of distinct, non-empty tags.
+func renderTags(tmpl *template.Template, e Entry) ([]string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, e); err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range strings.FieldsFunc(buf.String(), func(r rune) bool { return r == ',' || r == '\n' }) {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// truncateBody cuts a markdown body to at most n runes, appending a link to
+// the full release notes at url when it had to be shortened.
+func truncateBody(body string, n int, url string) (string, bool) {
+	r := []rune(body)
+	if len(r) <= n {
+		return body, false
+	}
+
+	body = strings.TrimRightFunc(string(r[:n]), unicode.IsSpace) + "…"
+	if url != "" {
+		body += "\n\n[Read more](" + url + ")"
+	}
+	return body, true
+}
+
+var (
+	mentionRe   = regexp.MustCompile(`(?:^|[^\w@/.])@([A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?)\b`)
+	referenceRe = regexp.MustCompile(`(?:^|[^\w&/#])#(\d+)\b`)
+)
+
+// mentions collects the distinct @user mentions and #123 issue references
+// of a markdown body, in order of appearance.
+func mentions(body string) (users, refs []string) {
+	seen := make(map[string]bool)
+	for _, m := range mentionRe.FindAllStringSubmatch(body, -1) {
+		if key := "@" + strings.ToLower(m[1]); !seen[key] {
+			seen[key] = true
+			users = append(users, m[1])
+		}
+	}
+	for _, m := range referenceRe.FindAllStringSubmatch(body, -1) {
+		if key := "#" + m[1]; !seen[key] {
+			seen[key] = true
+			refs = append(refs, key)
+		}
+	}
+	return users, refs
+}
+
+// readingTime counts the words in a markdown body and estimates the minutes
+// needed to read them at wpm words per minute, rounding up.
+func readingTime(body string, wpm int) (words, minutes int) {
+	for _, w := range strings.Fields(body) {
+		if strings.IndexFunc(w, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			words++
+		}
+	}
+	if words == 0 {
+		return 0, 0
+	}
+	return words, (words + wpm - 1) / wpm
+}
+
+// compileFilter compiles the regexp given to the named flag. An empty
+// pattern disables the filter and yields a nil regexp.
+func compileFilter(name, pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -%s regexp %q: %s", name, pattern, err)
+	}
+	return re, nil
+}
+
+// idFilenames names files after a hash of the entry ID instead of the title.
+var idFilenames bool
+
+// idSlugLen is the number of hex digits of the SHA-256 of the entry ID used
+// as its slug with -id-filenames.
+const idSlugLen = 12
+
+// authorMatches reports whether the name of a, or the Github login of its
+// profile URI, is one of names, ignoring case.
+func authorMatches(a Author, names []string) bool {
+	login := githubLogin(a.Uri)
+	for _, name := range names {
+		if strings.EqualFold(name, strings.TrimSpace(a.Name)) || (login != "" && strings.EqualFold(name, login)) {
+			return true
+		}
+	}
+	return false
+}
+
+// entrySlug returns the base name, without extension, of the files
+// generated for an entry.
+func entrySlug(e Entry) string {
+	if e.slug != "" {
+		return e.slug
+	}
+	if idFilenames {
+		sum := sha256.Sum256([]byte(e.ID))
+		return hex.EncodeToString(sum[:])[:idSlugLen]
+	}
+	return makePath(e.Title)
+}
+
+// renderEntry executes the output's template for e.
+func renderEntry(out output, e Entry) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := out.template(e).Execute(&buf, e); err != nil {
+		return nil, err
+	}
+	return endPost(renameKeys(buf.Bytes())), nil
+}
+
+// trailingNewline is how posts end: "one" newline, "none", or "raw" to keep
+// whatever the template and body conversion produced.
+var trailingNewline = "one"
+
+// endPost applies trailingNewline to a rendered post.
+func endPost(b []byte) []byte {
+	switch trailingNewline {
+	case "one":
+		return append(bytes.TrimRight(b, "\r\n"), '\n')
+	case "none":
+		return bytes.TrimRight(b, "\r\n")
+	}
+	return b
+}
+
+// archiveEntry adds the rendered entry to the archive using the same layout
+// writeEntry would use on disk, nested under the output directory when one
+// was given.
+func archiveEntry(a *archiveWriter, out output, e Entry) error {
+	b, err := renderEntry(out, e)
+	if err != nil {
+		return err
+	}
+	return a.AddFile(out.path(e, ".md"), b)
+}
+
+// printEntry writes the rendered entry to stdout, preceded by a header line
+// with its file name.
+func printEntry(out output, e Entry) error {
+	b, err := renderEntry(out, e)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("==> %s <==\n", out.path(e, ".md"))
+	if _, err = os.Stdout.Write(b); err != nil {
+		return err
+	}
+	if !bytes.HasSuffix(b, []byte("\n")) {
+		// Keep the next header on a line of its own.
+		fmt.Println()
+	}
+	return nil
+}
+
+// listEntries writes a table of the entries' titles, dates, versions, slugs
+// and links to w.
+func listEntries(w io.Writer, entries []Entry, noVersion bool) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TITLE\tDATE\tVERSION\tSLUG\tLINK")
+	for _, e := range entries {
+		if strings.TrimSpace(e.Title) == "" {
+			e.Title = synthesizeTitle(e)
+		}
+		version := e.Title
+		if noVersion {
+			version = ""
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.Title, yearMonthDate(e.Date), version, entrySlug(e), e.Links.Alternate().Href)
+	}
+	return tw.Flush()
+}
+
+// diffEntry prints a unified diff between the existing file for the entry
+// and its rendered output, reporting whether they differ.
+func diffEntry(out output, e Entry) (bool, error) {
+	b, err := renderEntry(out, e)
+	if err != nil {
+		return false, err
+	}
+
+	filename := out.path(e, ".md")
+	old, err := destination.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	d := unifiedDiff(string(old), string(b), filename, filename)
+	if d == "" {
+		return false, nil
+	}
+	fmt.Print(d)
+	return true, nil
+}
+
+// checkEntry reports whether the entry's file exists and holds exactly what
+// would be written for it.
+func checkEntry(out output, e Entry) (bool, error) {
+	b, err := renderEntry(out, e)
+	if err != nil {
+		return false, err
+	}
+
+	old, err := destination.ReadFile(out.path(e, ".md"))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(old, b), nil
+}
+
+// writeEntry writes the entry to the output directory, reporting whether it
+// was written. An existing file is left alone unless overwrite is set.
+func writeEntry(out output, e Entry, overwrite bool) (bool, error) {
+	b, err := renderEntry(out, e)
+	if err != nil {
+		return false, err
+	}
+	return writeFile(out.path(e, ".md"), b, overwrite)
+}
+
+// writeFile writes data to filename on the destination, reporting whether
+// it was written. An existing file is left alone unless overwrite is set.
+func writeFile(filename string, data []byte, overwrite bool) (bool, error) {
+	if !overwrite && destination.Exists(filename) {
+		return false, nil
+	}
+	if err := destination.WriteFile(filename, data); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// preserveSlugCase disables lowercasing in makePath.
+var preserveSlugCase bool
+
+// slugSeparator replaces spaces in makePath.
+var slugSeparator = "-"
+
+// validSlugSeparator reports whether sep may be used as slugSeparator. Only
+// characters that unicodeSanitize keeps are allowed.
+func validSlugSeparator(sep string) bool {
+	return sep == "-" || sep == "_" || sep == "."
+}
+
+// Take a string with any characters and replace it so the string could be used in a path.
+// E.g. Social Media -> social-media
+func makePath(s string) string {
+	s = strings.Replace(strings.TrimSpace(s), " ", slugSeparator, -1)
+	if !preserveSlugCase {
+		s = strings.ToLower(s)
+	}
+	return unicodeSanitize(s)
 }
 
 func unicodeSanitize(s string) string {
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// run reports what it does on the log, which would bury test failures.
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// convert runs the convert command with args on feed, writing the posts into
+// a new temporary directory, which it returns.
+func convert(t testing.TB, feed []byte, args ...string) string {
+	t.Helper()
+	o := &options{}
+	if err := parseArgs(o, append([]string{"-quiet"}, args...)...); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := run(o, feed, dir); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// parseArgs sets o from the flags of the convert command in args.
+func parseArgs(o *options, args ...string) error {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	o.register(fs)
+	return fs.Parse(args)
+}
+
+// multiLinkFeed is a release entry with the kinds of links feeds combine:
+// the release page, the entry itself, a discussion and two assets.
+const multiLinkFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <id>tag:github.com,2008:https://github.com/owner/repo/releases</id>
+  <title>Release notes from repo</title>
+  <updated>2023-04-02T01:30:00Z</updated>
+  <entry>
+    <id>tag:github.com,2008:Repository/1/v1.2.0</id>
+    <updated>2023-04-02T01:30:00Z</updated>
+    <link rel="self" type="application/atom+xml" href="https://github.com/owner/repo/releases/v1.2.0.atom"/>
+    <link rel="alternate" type="text/html" href="https://github.com/owner/repo/releases/tag/v1.2.0"/>
+    <link rel="related" type="text/html" href="https://github.com/owner/repo/discussions/12"/>
+    <link rel="enclosure" type="application/gzip" title="repo_linux_amd64.tar.gz" href="https://github.com/owner/repo/releases/download/v1.2.0/repo_linux_amd64.tar.gz"/>
+    <link rel="related" type="text/html" href="https://github.com/owner/repo/compare/v1.1.0...v1.2.0"/>
+    <link rel="enclosure" type="application/zip" href="https://github.com/owner/repo/releases/download/v1.2.0/repo_windows_amd64.zip"/>
+    <title>v1.2.0</title>
+    <content type="html">&lt;p&gt;Notes&lt;/p&gt;</content>
+    <author><name>alice</name></author>
+  </entry>
+</feed>
+`
+
+func multiLinkEntry(t *testing.T) Entry {
+	t.Helper()
+	exp, err := parseFeed([]byte(multiLinkFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exp.Entries) != 1 || len(exp.Entries[0].Links) != 6 {
+		t.Fatalf("parsed %d entries, want 1 with 6 links", len(exp.Entries))
+	}
+	return exp.Entries[0]
+}
+
+func TestLinksSelectors(t *testing.T) {
+	links := multiLinkEntry(t).Links
+
+	if got, want := links.Alternate().Href, "https://github.com/owner/repo/releases/tag/v1.2.0"; got != want {
+		t.Errorf("Alternate() = %q, want %q", got, want)
+	}
+	if got, want := links.Self().Href, "https://github.com/owner/repo/releases/v1.2.0.atom"; got != want {
+		t.Errorf("Self() = %q, want %q", got, want)
+	}
+	if got, want := hrefs(links.Related()), []string{
+		"https://github.com/owner/repo/discussions/12",
+		"https://github.com/owner/repo/compare/v1.1.0...v1.2.0",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Related() = %q, want %q", got, want)
+	}
+	if got, want := hrefs(links.Enclosures()), []string{
+		"https://github.com/owner/repo/releases/download/v1.2.0/repo_linux_amd64.tar.gz",
+		"https://github.com/owner/repo/releases/download/v1.2.0/repo_windows_amd64.zip",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Enclosures() = %q, want %q", got, want)
+	}
+	if got, want := links.first("enclosure").Title, "repo_linux_amd64.tar.gz"; got != want {
+		t.Errorf("first(enclosure).Title = %q, want %q", got, want)
+	}
+}
+
+func TestLinksMissingRel(t *testing.T) {
+	links := Links{
+		{Href: "https://github.com/owner/repo/releases/v1.2.0.atom", Rel: "self"},
+		{Href: "https://github.com/owner/repo/releases/tag/v1.2.0"},
+	}
+
+	// A link without a rel is an alternate link, as in Atom.
+	if got, want := links.Alternate().Href, "https://github.com/owner/repo/releases/tag/v1.2.0"; got != want {
+		t.Errorf("Alternate() = %q, want %q", got, want)
+	}
+	if got := links.Related(); len(got) != 0 {
+		t.Errorf("Related() = %q, want none", hrefs(got))
+	}
+	if got := links.Enclosures(); len(got) != 0 {
+		t.Errorf("Enclosures() = %q, want none", hrefs(got))
+	}
+
+	// Without a matching link, the selectors return an empty Link.
+	if got := (Links{{Href: "https://example.com/", Rel: "alternate"}}).Self(); got != (Link{}) {
+		t.Errorf("Self() = %+v, want an empty Link", got)
+	}
+	if got := Links(nil).Alternate(); got != (Link{}) {
+		t.Errorf("Alternate() of no links = %+v, want an empty Link", got)
+	}
+}
+
+func hrefs(links Links) []string {
+	var s []string
+	for _, l := range links {
+		s = append(s, l.Href)
+	}
+	return s
+}
+
+func TestByteOrderMark(t *testing.T) {
+	tests := []struct {
+		file   string
+		format string
+	}{
+		{"bom.atom", "atom"},
+		{"bom-utf16.atom", "atom"},
+		{"bom.rss", "rss"},
+		{"bom.json", "json"},
+	}
+	for _, tt := range tests {
+		for _, stdin := range []bool{false, true} {
+			name := tt.file
+			if stdin {
+				name += " on stdin"
+			}
+			t.Run(name, func(t *testing.T) {
+				path := filepath.Join("testdata", tt.file)
+				if stdin {
+					f, err := os.Open(path)
+					if err != nil {
+						t.Fatal(err)
+					}
+					defer f.Close()
+					defer func(stdin *os.File) { os.Stdin = stdin }(os.Stdin)
+					os.Stdin = f
+					path = "-"
+				}
+				b, err := readInput(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				format := "atom"
+				if isJSON(b) {
+					format = "json"
+				} else if isRSS(b) {
+					format = "rss"
+				}
+				if format != tt.format {
+					t.Errorf("detected %s, want %s", format, tt.format)
+				}
+
+				post, err := os.ReadFile(filepath.Join(convert(t, b), "v1.2.0.md"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(post), "title: \"repo: v1.2.0\"") || !strings.Contains(string(post), "Notes") {
+					t.Errorf("unexpected post:\n%s", post)
+				}
+			})
+		}
+	}
+}
+
+func TestStampIsStable(t *testing.T) {
+	feed, err := os.ReadFile(filepath.Join("testdata", "releases.atom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(c func() time.Time) { clock = c }(clock)
+	clock = func() time.Time { return time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC) }
+	dir := convert(t, feed, "-stamp")
+
+	post, err := os.ReadFile(filepath.Join(dir, "v1.2.0.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(post), " at 2023-04-02T01:30:00Z -->") {
+		t.Errorf("the stamp does not have the time the release was updated:\n%s", post)
+	}
+
+	// A later run finds the posts up to date.
+	clock = func() time.Time { return time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC) }
+	o := &options{}
+	if err := parseArgs(o, "-quiet", "-stamp", "-check"); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(o, feed, dir); err != nil {
+		t.Errorf("-stamp -check on the posts of the same feed: %v", err)
+	}
+}
+
+func TestChangelogRepoPrefix(t *testing.T) {
+	feed, err := os.ReadFile(filepath.Join("testdata", "releases.atom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{nil, "\nchangelog:\n- Tools\nversion:"},
+		{[]string{"-repo-prefix", "github.com/owner/"}, "\nchangelog:\n- Tools\n- \"github.com/owner/repo\"\nversion:"},
+	} {
+		post, err := os.ReadFile(filepath.Join(convert(t, feed, tt.args...), "v1.2.0.md"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(post), tt.want) {
+			t.Errorf("%q: post does not contain %q:\n%s", tt.args, tt.want, post)
+		}
+	}
+}
+
+func TestEmptyBody(t *testing.T) {
+	tests := []struct {
+		body     string
+		markdown bool
+		want     bool
+	}{
+		{"", false, true},
+		{" \n", true, true},
+		{"<p></p>\n<p> <br></p>", false, true},
+		{"<p>&nbsp;</p><!-- note -->", false, true},
+		{"<script>track()</script>", false, true},
+		{"<p>Fixed</p>", false, false},
+		{"<p><img src=\"x.png\"></p>", false, false},
+		{"<hr>", false, false},
+		{"<ul><li><input type=\"checkbox\" disabled></li></ul>", false, false},
+		{"<p></p>", true, false},
+	}
+	for _, tt := range tests {
+		if got := emptyBody(tt.body, tt.markdown); got != tt.want {
+			t.Errorf("emptyBody(%q, %v) = %v, want %v", tt.body, tt.markdown, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ageFlag is the -max-age duration. On top of time.ParseDuration units it
+// accepts days, e.g. 30d.
+type ageFlag time.Duration
+
+func (f *ageFlag) String() string {
+	if *f == 0 {
+		return ""
+	}
+	return time.Duration(*f).String()
+}
+
+func (f *ageFlag) Set(s string) error {
+	if days := strings.TrimSuffix(s, "d"); days != s {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid age %q", s)
+		}
+		*f = ageFlag(n * float64(24*time.Hour))
+		return nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid age %q, use e.g. 720h or 30d", s)
+	}
+	*f = ageFlag(d)
+	return nil
+}
+
+// postDate returns the date in the frontmatter of the post at filename. ok
+// is false when the post has no date releasetoblog can read.
+func postDate(filename string) (date time.Time, ok bool, err error) {
+	b, err := destination.ReadFile(filename)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	fm, _, found := splitFrontmatter(string(b))
+	if !found {
+		return time.Time{}, false, nil
+	}
+	key := mappedKey("date")
+	for _, k := range frontmatterKeys(fm) {
+		if k.name != key {
+			continue
+		}
+		value := strings.TrimSpace(k.text[strings.Index(k.text, key+":")+len(key)+1:])
+		value = strings.Trim(value, `"'`)
+		for _, layout := range []string{dateLayout, time.RFC3339, "2006-01-02"} {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t, true, nil
+			}
+		}
+	}
+	return time.Time{}, false, nil
+}
+
+// expire removes the files of entries in the state whose posts are dated
+// before cutoff, and forgets the entries. Only files recorded in the state
+// are considered, so posts not written by releasetoblog are never removed.
+// It returns the number of entries expired and the files removed.
+func expire(st *state, cutoff time.Time) (int, []string, error) {
+	var files []string
+	expired := 0
+	ids := make([]string, 0, len(st.Posts))
+	for id := range st.Posts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		filenames := st.Posts[id]
+		var date time.Time
+		found := false
+		for _, filename := range filenames {
+			if filepath.Ext(filename) != ".md" {
+				continue
+			}
+			d, ok, err := postDate(filename)
+			if err != nil && !os.IsNotExist(err) {
+				return expired, files, fmt.Errorf("Failed reading the date of %s:\n%w", filename, err)
+			}
+			if ok {
+				date, found = d, true
+				break
+			}
+		}
+		if !found || !date.Before(cutoff) {
+			continue
+		}
+
+		for _, filename := range filenames {
+			err := destination.Remove(filename)
+			if err != nil && !os.IsNotExist(err) {
+				return expired, files, fmt.Errorf("Failed removing %s:\n%w", filename, err)
+			}
+			if err == nil {
+				files = append(files, filename)
+				log.Printf("Removed %s, dated %s, older than -max-age.", filename, yearMonthDate(Date(date)))
+			}
+		}
+		delete(st.Posts, id)
+		expired++
+	}
+	return expired, files, nil
+}
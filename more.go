@@ -0,0 +1,85 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/lunny/html2md"
+	"golang.org/x/net/html"
+)
+
+// moreRe matches Hugo's summary divider, <!--more-->.
+var moreRe = regexp.MustCompile(`<!--\s*more\s*-->`)
+
+// moreMarker is the summary divider written to posts.
+const moreMarker = "<!--more-->"
+
+// isMore reports whether n is a summary divider comment.
+func isMore(n *html.Node) bool {
+	return n.Type == html.CommentNode && strings.TrimSpace(n.Data) == "more"
+}
+
+// convertBody converts an html body to markdown. html2md drops comments, so
+// the halves around a summary divider are converted separately and joined
+// with the divider again. It also drops checkboxes, so task list items get
+// their markers first, and their lists the "- " bullets Github writes.
+func convertBody(body string) string {
+	marked, err := taskMarkers(body)
+	if err != nil || marked == body {
+		return convertHalves(body)
+	}
+	return taskListMarkdown(convertHalves(marked))
+}
+
+// convertHalves converts the html body, keeping its summary divider.
+func convertHalves(body string) string {
+	loc := moreRe.FindStringIndex(body)
+	if loc == nil {
+		return html2md.Convert(body)
+	}
+	before := strings.TrimRight(html2md.Convert(body[:loc[0]]), "\n")
+	after := strings.TrimLeft(html2md.Convert(body[loc[1]:]), "\n")
+	return before + "\n\n" + moreMarker + "\n\n" + after
+}
+
+// insertMore adds a summary divider after the first paragraph of a body
+// that has none. Headings do not count as paragraphs.
+func insertMore(body string, markdown bool) (string, error) {
+	if moreRe.MatchString(body) {
+		return body, nil
+	}
+
+	if markdown {
+		blocks := strings.Split(body, "\n\n")
+		for i, b := range blocks {
+			if t := strings.TrimSpace(b); t != "" && !strings.HasPrefix(t, "#") {
+				if i == len(blocks)-1 {
+					break
+				}
+				return strings.Join(blocks[:i+1], "\n\n") + "\n\n" + moreMarker + "\n\n" + strings.Join(blocks[i+1:], "\n\n"), nil
+			}
+		}
+		return body, nil
+	}
+
+	nodes, err := parseBody(body)
+	if err != nil {
+		return "", err
+	}
+	for i, n := range nodes {
+		if n.Type != html.ElementNode || strings.TrimSpace(textContent(n)) == "" {
+			continue
+		}
+		switch n.Data {
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			continue
+		}
+		if i == len(nodes)-1 {
+			break
+		}
+		more := &html.Node{Type: html.CommentNode, Data: "more"}
+		nodes = append(nodes[:i+1], append([]*html.Node{more}, nodes[i+1:]...)...)
+		return renderNodes(nodes)
+	}
+	return body, nil
+}
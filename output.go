@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// output is a tree of posts rendered with one template.
+type output struct {
+	dir  string
+	tmpl *template.Template
+	// datePrefix prepends the post date to file names, as Jekyll requires
+	// for its _posts directory.
+	datePrefix bool
+	// dateFolders nests files in folders named after the post date, with
+	// a granularity of year, month or day.
+	dateFolders string
+	// lang is inserted before the extension of file names, as Hugo's
+	// translation by file name expects.
+	lang string
+	// rules select another template for some entries.
+	rules []templateRule
+	// prereleaseDir, when set, holds the posts of pre-releases instead of
+	// dir.
+	prereleaseDir string
+}
+
+// templateRule renders the entries it matches with its own template.
+type templateRule struct {
+	// match selects entries by title. A nil match selects pre-releases.
+	match *regexp.Regexp
+	tmpl  *template.Template
+}
+
+// prereleaseRule is the -template-for predicate matching pre-releases.
+const prereleaseRule = "@prerelease"
+
+// parseTemplateRule parses a -template-for value of the form
+// predicate=file, where predicate is a regular expression on the title or
+// @prerelease.
+func parseTemplateRule(spec string) (templateRule, error) {
+	i := strings.LastIndex(spec, "=")
+	if i < 0 {
+		return templateRule{}, fmt.Errorf("invalid -template-for %q: expected predicate=file", spec)
+	}
+	predicate, file := spec[:i], spec[i+1:]
+
+	var rule templateRule
+	if predicate != prereleaseRule {
+		re, err := regexp.Compile(predicate)
+		if err != nil {
+			return templateRule{}, fmt.Errorf("invalid -template-for %q: %s", spec, err)
+		}
+		rule.match = re
+	}
+
+	tmpl, err := loadTemplate(file)
+	if err != nil {
+		return templateRule{}, fmt.Errorf("invalid -template-for %q: %s", spec, err)
+	}
+	rule.tmpl = tmpl
+	return rule, nil
+}
+
+// template returns the template of the first rule matching e, or the
+// output's own template.
+func (out output) template(e Entry) *template.Template {
+	for _, r := range out.rules {
+		if (r.match == nil && e.Prerelease) || (r.match != nil && r.match.MatchString(e.Title)) {
+			return r.tmpl
+		}
+	}
+	return out.tmpl
+}
+
+// forEntry returns the output that e is written to, moved to the
+// prereleaseDir for pre-releases.
+func (out output) forEntry(e Entry) output {
+	if e.Prerelease && out.prereleaseDir != "" {
+		out.dir = out.prereleaseDir
+	}
+	return out
+}
+
+// destinations returns out and, when it has a prereleaseDir, out moved
+// there, for the steps done once per directory.
+func (out output) destinations() []output {
+	dests := []output{out}
+	if out.prereleaseDir != "" {
+		pre := out
+		pre.dir = out.prereleaseDir
+		dests = append(dests, pre)
+	}
+	return dests
+}
+
+// dateFolderLayouts are the time layouts of the -date-folders granularities.
+var dateFolderLayouts = map[string]string{
+	"year":  "2006",
+	"month": "2006/01",
+	"day":   "2006/01/02",
+}
+
+// filename returns the path of the entry's file with the given extension,
+// relative to the output directory.
+func (out output) filename(e Entry, ext string) string {
+	// The date prefix and folders use the effective time of the frontmatter
+	// date, see Date.Time, so a release lands on the same day in all three.
+	name := entrySlug(e) + out.langSuffix() + ext
+	if out.datePrefix {
+		name = yearMonthDate(e.Date) + "-" + name
+	}
+	if out.dateFolders != "" {
+		name = filepath.Join(filepath.FromSlash(e.Date.Time().Format(dateFolderLayouts[out.dateFolders])), name)
+	}
+	return name
+}
+
+// langSuffix returns the ".lang" part of the output's file names, if any.
+func (out output) langSuffix() string {
+	if out.lang == "" {
+		return ""
+	}
+	return "." + out.lang
+}
+
+// path returns the path of the entry's file with the given extension,
+// including the output directory.
+func (out output) path(e Entry, ext string) string {
+	return filepath.Join(out.dir, out.filename(e, ext))
+}
+
+var jekyllTempl = `---
+layout: post
+title: "{{ yaml .Repo }}: {{ yaml .Title }}{{ yaml .TitleDate }}"
+date: {{ .Date }}
+description: "{{ yaml .Description }}"
+categories:
+- Tools
+{{- range .Changelog }}
+- "{{ yaml . }}"
+{{- end }}
+{{- if .Version }}
+version: "{{ yaml .Version }}"
+{{- end }}
+{{- if .Draft }}
+published: false
+{{- end }}
+{{- if .Tags }}
+tags:
+{{- range .Tags }}
+- "{{ yaml . }}"
+{{- end }}
+{{- end }}
+{{- if .Lang }}
+lang: "{{ yaml .Lang }}"
+{{- end }}
+{{- if .Cover }}
+image: "{{ yaml .Cover }}"
+{{- end }}
+author: "{{ yaml .Author.Name }}"
+{{- if .Extra }}
+{{ .Extra }}
+{{- end }}
+---
+
+{{ .Content }}
+{{- if .Stamp }}
+
+{{ .Stamp }}
+{{- end }}
+`
+
+// formats are the built-in output formats selectable with -output.
+var formats = map[string]output{
+	"hugo":   {tmpl: t},
+	"jekyll": {tmpl: template.Must(template.New("jekyll").Funcs(funcMap).Parse(jekyllTempl)), datePrefix: true},
+}
+
+// parseOutput parses an -output value of the form format:dir, where format
+// is a built-in format name or the path of a template file.
+func parseOutput(spec string) (output, error) {
+	i := strings.Index(spec, ":")
+	if i < 0 {
+		return output{}, fmt.Errorf("invalid -output %q: expected format:dir", spec)
+	}
+	format, dir := spec[:i], spec[i+1:]
+	if dir == "" {
+		return output{}, fmt.Errorf("invalid -output %q: missing directory", spec)
+	}
+
+	if out, ok := formats[format]; ok {
+		out.dir = dir
+		return out, nil
+	}
+
+	tmpl, err := loadTemplate(format)
+	if err != nil {
+		return output{}, fmt.Errorf("invalid -output %q: %s", spec, err)
+	}
+	return output{dir: dir, tmpl: tmpl}, nil
+}
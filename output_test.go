@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// lateFeed has a release published late in the evening UTC, already the next
+// day in Tokyo, and one published in the early hours UTC, still the day
+// before in Los Angeles.
+const lateFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <id>tag:github.com,2008:https://github.com/owner/repo/releases</id>
+  <title>Release notes from repo</title>
+  <updated>2023-04-02T01:30:00Z</updated>
+  <entry>
+    <id>tag:github.com,2008:Repository/1/v1.2.0</id>
+    <updated>%s</updated>
+    <link rel="alternate" type="text/html" href="https://github.com/owner/repo/releases/tag/v1.2.0"/>
+    <title>v1.2.0</title>
+    <content type="html">&lt;p&gt;Notes&lt;/p&gt;</content>
+    <author><name>alice</name></author>
+  </entry>
+</feed>
+`
+
+func TestEffectiveDate(t *testing.T) {
+	tests := []struct {
+		updated  string
+		timezone string
+		day      string
+	}{
+		{"2023-04-01T23:30:00Z", "", "2023-04-01"},
+		{"2023-04-01T23:30:00Z", "Asia/Tokyo", "2023-04-02"},
+		{"2023-04-02T01:30:00Z", "UTC", "2023-04-02"},
+		{"2023-04-02T01:30:00Z", "America/Los_Angeles", "2023-04-01"},
+		{"2023-04-02T01:30:00+09:00", "", "2023-04-02"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.updated+" "+tt.timezone, func(t *testing.T) {
+			jekyll := t.TempDir()
+			args := []string{"-date-folders", "day", "-date-format", "date", "-output", "jekyll:" + jekyll}
+			if tt.timezone != "" {
+				args = append(args, "-timezone", tt.timezone)
+			}
+			dir := convert(t, []byte(fmt.Sprintf(lateFeed, tt.updated)), args...)
+
+			folder := filepath.FromSlash(strings.ReplaceAll(tt.day, "-", "/"))
+			post := filepath.Join(dir, folder, "v1.2.0.md")
+			jekyllPost := filepath.Join(jekyll, folder, tt.day+"-v1.2.0.md")
+			for _, name := range []string{post, jekyllPost} {
+				b, err := os.ReadFile(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if date := "\ndate: " + tt.day + "\n"; !strings.Contains(string(b), date) {
+					t.Errorf("%s does not have %q:\n%s", name, strings.TrimSpace(date), b)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// progressDelay passes before the first report, so that short runs
+	// stay quiet.
+	progressDelay = time.Second
+	// progressRedraw is how often the progress bar is redrawn on a terminal.
+	progressRedraw = 100 * time.Millisecond
+	// progressLogEvery is how often progress is logged when stderr is not a
+	// terminal.
+	progressLogEvery = 10 * time.Second
+	progressWidth    = 30
+)
+
+// progress reports how far a long run has got on stderr: as a bar redrawn
+// in place on a terminal, or else as a log line now and then. It is also the
+// log output while active, clearing the bar before each log line so that
+// lines are never mixed with it. A nil progress reports nothing.
+type progress struct {
+	w     io.Writer
+	tty   bool
+	label string
+	total int
+	start time.Time
+	last  time.Time
+	// drawn is set while the bar is on the terminal's current line.
+	drawn bool
+}
+
+// newProgress starts reporting progress on stderr and makes it the log
+// output until finish is called. With quiet set it returns nil.
+func newProgress(label string, total int, quiet bool) *progress {
+	if quiet {
+		return nil
+	}
+	p := &progress{w: os.Stderr, tty: isTerminal(os.Stderr), label: label, total: total, start: time.Now()}
+	log.SetOutput(p)
+	return p
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// update reports that done of the total items are processed.
+func (p *progress) update(done int) {
+	if p == nil {
+		return
+	}
+	now := time.Now()
+	if now.Sub(p.start) < progressDelay {
+		return
+	}
+	if !p.tty {
+		if now.Sub(p.last) >= progressLogEvery {
+			p.last = now
+			log.Printf("%s: %d of %d releases...", p.label, done, p.total)
+		}
+		return
+	}
+	if now.Sub(p.last) < progressRedraw && done < p.total {
+		return
+	}
+	p.last = now
+
+	filled := progressWidth
+	if p.total > 0 {
+		filled = progressWidth * done / p.total
+	}
+	fmt.Fprintf(p.w, "\r%s [%s%s] %d/%d", p.label, strings.Repeat("=", filled), strings.Repeat(" ", progressWidth-filled), done, p.total)
+	p.drawn = true
+}
+
+// Write clears the bar and writes a log line.
+func (p *progress) Write(b []byte) (int, error) {
+	p.clear()
+	return p.w.Write(b)
+}
+
+// clear erases the bar from the terminal's current line.
+func (p *progress) clear() {
+	if p.drawn {
+		fmt.Fprint(p.w, "\r\033[K")
+		p.drawn = false
+	}
+}
+
+// finish removes the bar and restores the log output.
+func (p *progress) finish() {
+	if p == nil {
+		return
+	}
+	p.clear()
+	log.SetOutput(p.w)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// release is a release in the JSON returned by the Github REST API at
+// /repos/{owner}/{repo}/releases.
+type release struct {
+	ID          int64     `json:"id"`
+	HTMLURL     string    `json:"html_url"`
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Body        string    `json:"body"`
+	Draft       bool      `json:"draft"`
+	Prerelease  bool      `json:"prerelease"`
+	CreatedAt   time.Time `json:"created_at"`
+	PublishedAt time.Time `json:"published_at"`
+	Author      struct {
+		Login   string `json:"login"`
+		HTMLURL string `json:"html_url"`
+	} `json:"author"`
+	Assets []struct {
+		Name        string `json:"name"`
+		ContentType string `json:"content_type"`
+		URL         string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// isJSON reports whether b looks like a JSON array rather than a feed.
+func isJSON(b []byte) bool {
+	b = bytes.TrimLeft(bytes.TrimPrefix(b, []byte("\ufeff")), " \t\r\n")
+	return len(b) > 0 && b[0] == '['
+}
+
+// parseReleases decodes the Github REST API releases JSON into the same
+// shape as a feed. Bodies are already markdown and are not converted.
+func parseReleases(b []byte) (Export, error) {
+	var releases []release
+	if err := json.Unmarshal(bytes.TrimPrefix(b, []byte("\ufeff")), &releases); err != nil {
+		return Export{}, fmt.Errorf("invalid releases JSON: %s", err)
+	}
+
+	exp := Export{}
+	for _, r := range releases {
+		if exp.Title == "" {
+			if repo, _, ok := releaseTag(r.HTMLURL); ok {
+				exp.ID = "https://github.com/" + repo + "/releases"
+				exp.Title = defaultReleasePrefix + repo[strings.Index(repo, "/")+1:]
+			}
+		}
+
+		title := r.Name
+		if strings.TrimSpace(title) == "" {
+			title = r.TagName
+		}
+		published := r.PublishedAt
+		if published.IsZero() {
+			// Drafts have not been published yet.
+			published = r.CreatedAt
+		}
+
+		e := Entry{
+			ID:         fmt.Sprintf("tag:github.com,2008:Repository/%d/%s", r.ID, r.TagName),
+			Updated:    Date(published),
+			Published:  Date(published),
+			Title:      title,
+			Content:    strings.ReplaceAll(r.Body, "\r\n", "\n"),
+			Links:      Links{{Href: r.HTMLURL, Rel: "alternate", Type: "text/html"}},
+			Author:     Author{Name: r.Author.Login, Uri: r.Author.HTMLURL},
+			Prerelease: r.Prerelease,
+			Draft:      r.Draft,
+			markdown:   true,
+		}
+		for _, a := range r.Assets {
+			e.Links = append(e.Links, Link{Href: a.URL, Rel: "enclosure", Type: a.ContentType, Title: a.Name})
+		}
+		exp.Entries = append(exp.Entries, e)
+	}
+	return exp, nil
+}
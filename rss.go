@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+)
+
+// rssFeed is an RSS 2.0 feed.
+type rssFeed struct {
+	XMLName xml.Name `xml:"rss"`
+	Channel struct {
+		Title         string    `xml:"title"`
+		Link          string    `xml:"link"`
+		Description   string    `xml:"description"`
+		LastBuildDate Date      `xml:"lastBuildDate"`
+		Items         []rssItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+// rssItem is an item of an RSS 2.0 feed, with the commonly used Dublin Core
+// creator and full content extensions.
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	PubDate     Date   `xml:"pubDate"`
+	Description string `xml:"description"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Author      string `xml:"author"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Enclosures  []struct {
+		URL  string `xml:"url,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"enclosure"`
+}
+
+// isRSS reports whether the root element of the XML in b is <rss>.
+func isRSS(b []byte) bool {
+	if u, ok := decodeUTF16(b); ok {
+		b = u
+	}
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	dec.CharsetReader = feedCharsetReader
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return false
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name.Local == "rss"
+		}
+	}
+}
+
+// parseRSS decodes an RSS 2.0 feed into the same shape as an Atom feed.
+func parseRSS(b []byte) (Export, error) {
+	var feed rssFeed
+	if err := decodeFeed(b, &feed); err != nil {
+		return Export{}, err
+	}
+
+	ch := feed.Channel
+	exp := Export{
+		ID:       ch.Link,
+		Title:    ch.Title,
+		Subtitle: ch.Description,
+		Updated:  ch.LastBuildDate,
+	}
+	for _, item := range ch.Items {
+		e := Entry{
+			ID:        strings.TrimSpace(item.GUID),
+			Updated:   item.PubDate,
+			Published: item.PubDate,
+			Title:     item.Title,
+			Content:   item.Description,
+			Author:    Author{Name: item.Creator},
+		}
+		if e.ID == "" {
+			e.ID = item.Link
+		}
+		if item.Content != "" {
+			e.Content = item.Content
+		}
+		if e.Author.Name == "" {
+			e.Author.Name = rssAuthorName(item.Author)
+		}
+		if item.Link != "" {
+			e.Links = append(e.Links, Link{Href: item.Link, Rel: "alternate", Type: "text/html"})
+		}
+		for _, enc := range item.Enclosures {
+			e.Links = append(e.Links, Link{Href: enc.URL, Rel: "enclosure", Type: enc.Type})
+		}
+		exp.Entries = append(exp.Entries, e)
+	}
+	return exp, nil
+}
+
+// rssAuthorName returns the name in an RSS author, which is an email address
+// optionally followed by the name in parentheses.
+func rssAuthorName(author string) string {
+	author = strings.TrimSpace(author)
+	if i := strings.Index(author, " ("); i >= 0 && strings.HasSuffix(author, ")") {
+		return author[i+2 : len(author)-1]
+	}
+	return author
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// ugcAllow is the default -sanitize allowlist, modelled on the markup
+// Github itself allows in release notes. Entries are tag:attr,attr.
+var ugcAllow = []string{
+	"a:href,title", "abbr:title", "b", "blockquote:cite", "br", "code",
+	"dd", "del", "details:open", "div", "dl", "dt", "em", "g-emoji:alias",
+	"h1", "h2", "h3", "h4", "h5", "h6", "hr", "i",
+	"img:src,alt,title,width,height", "input:type,checked,disabled", "ins",
+	"kbd", "li", "ol:start", "p", "pre", "q:cite", "s", "samp", "small",
+	"span", "strike", "strong", "sub", "summary", "sup", "table", "tbody",
+	"td:align,colspan,rowspan", "tfoot", "th:align,colspan,rowspan", "thead",
+	"tr", "tt", "u", "ul", "var",
+}
+
+// globalAttrs are allowed on every allowed element.
+var globalAttrs = map[string]bool{"class": true, "dir": true, "lang": true}
+
+// urlAttrs hold URLs, which must be relative or use a safeScheme.
+var urlAttrs = map[string]bool{"href": true, "src": true, "cite": true}
+
+var safeSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// dropContent are the disallowed elements removed along with their content.
+// Other disallowed elements are unwrapped, keeping their children.
+var dropContent = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true,
+	"embed": true, "noscript": true, "template": true, "textarea": true,
+	"select": true, "svg": true, "math": true,
+}
+
+var allowRe = regexp.MustCompile(`^([a-z][a-z0-9-]*)(?::([a-z][a-z0-9-]*(?:,[a-z][a-z0-9-]*)*))?$`)
+
+// policy maps allowed elements to their allowed attributes.
+type policy map[string]map[string]bool
+
+// newPolicy builds a policy from the default allowlist and the -sanitize-allow
+// additions, each of the form tag or tag:attr,attr.
+func newPolicy(extra []string) (policy, error) {
+	p := make(policy)
+	for _, spec := range append(append([]string(nil), ugcAllow...), extra...) {
+		m := allowRe.FindStringSubmatch(strings.ToLower(strings.TrimSpace(spec)))
+		if m == nil {
+			return nil, fmt.Errorf("invalid -sanitize-allow %q: expected tag or tag:attr,attr", spec)
+		}
+		if p[m[1]] == nil {
+			p[m[1]] = make(map[string]bool)
+		}
+		if m[2] != "" {
+			for _, a := range strings.Split(m[2], ",") {
+				p[m[1]][a] = true
+			}
+		}
+	}
+	return p, nil
+}
+
+// sanitize removes the elements and attributes of an html release body that
+// the policy does not allow, reporting how many were removed.
+func (p policy) sanitize(body string) (string, int, error) {
+	nodes, err := parseBody(body)
+	if err != nil {
+		return "", 0, err
+	}
+
+	root := &html.Node{Type: html.ElementNode, Data: "body"}
+	for _, n := range nodes {
+		root.AppendChild(n)
+	}
+	removed := p.clean(root)
+
+	var kept []*html.Node
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		kept = append(kept, c)
+	}
+	for _, n := range kept {
+		root.RemoveChild(n)
+	}
+	out, err := renderNodes(kept)
+	return out, removed, err
+}
+
+func (p policy) clean(n *html.Node) int {
+	removed := 0
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch c.Type {
+		case html.CommentNode, html.DoctypeNode:
+			if isMore(c) {
+				break
+			}
+			n.RemoveChild(c)
+			removed++
+		case html.ElementNode:
+			allowed, ok := p[c.Data]
+			if !ok {
+				removed++
+				if first := c.FirstChild; first != nil && !dropContent[c.Data] {
+					for gc := first; gc != nil; {
+						gnext := gc.NextSibling
+						c.RemoveChild(gc)
+						n.InsertBefore(gc, c)
+						gc = gnext
+					}
+					next = first
+				}
+				n.RemoveChild(c)
+				break
+			}
+
+			var attrs []html.Attribute
+			for _, a := range c.Attr {
+				if a.Namespace == "" && (allowed[a.Key] || globalAttrs[a.Key]) && (!urlAttrs[a.Key] || safeURL(a.Val)) {
+					attrs = append(attrs, a)
+				} else {
+					removed++
+				}
+			}
+			c.Attr = attrs
+			if c.Data == "a" {
+				c.Attr = append(c.Attr, html.Attribute{Key: "rel", Val: "nofollow"})
+			}
+			removed += p.clean(c)
+		}
+		c = next
+	}
+	return removed
+}
+
+// safeURL reports whether u is relative or uses one of the safeSchemes.
+func safeURL(u string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(u))
+	if err != nil {
+		return false
+	}
+	return parsed.Scheme == "" || safeSchemes[strings.ToLower(parsed.Scheme)]
+}
+
+// autolinkRe matches a markdown autolink, which looks like an html tag.
+var autolinkRe = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9+.-]{1,31}:[^\s<>]*|[^\s<>@]+@[^\s<>@]+)>`)
+
+// sanitizeMarkdown removes the inline html of a markdown body that the
+// policy does not allow, leaving the markdown, fenced code blocks and `code`
+// spans as they are. It reports how many elements and attributes were removed.
+func (p policy) sanitizeMarkdown(body string) (string, int) {
+	f := &markdownFilter{p: p}
+	lines := strings.Split(body, "\n")
+	fenced := false
+	for i, line := range lines {
+		if i > 0 {
+			f.text("\n")
+		}
+		trimmed := strings.TrimLeft(line, " ")
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+			f.text(line)
+			continue
+		}
+		if fenced {
+			f.text(line)
+			continue
+		}
+		// Even parts are outside of code spans.
+		for j, part := range strings.Split(line, "`") {
+			if j > 0 {
+				f.text("`")
+			}
+			if j%2 == 1 {
+				f.text(part)
+			} else {
+				f.html(part)
+			}
+		}
+	}
+	return f.out.String(), f.removed
+}
+
+// markdownFilter writes the parts of a markdown body, cleaning those that
+// may hold html. Elements removed with their content, such as scripts, may
+// span several parts.
+type markdownFilter struct {
+	p       policy
+	out     strings.Builder
+	dropped int // open dropContent elements
+	removed int
+}
+
+// text writes markdown that holds no html, unless it is the content of a
+// dropped element.
+func (f *markdownFilter) text(s string) {
+	if f.dropped == 0 {
+		f.out.WriteString(s)
+	}
+}
+
+// html writes markdown that may hold html, keeping only the elements and
+// attributes the policy allows.
+func (f *markdownFilter) html(s string) {
+	for s != "" {
+		i := strings.IndexAny(s, `<\`)
+		if i < 0 {
+			f.text(s)
+			return
+		}
+		f.text(s[:i])
+		s = s[i:]
+
+		// Escaped characters and autolinks are markdown.
+		if s[0] == '\\' {
+			n := 1
+			if len(s) > 1 {
+				n = 2
+			}
+			f.text(s[:n])
+			s = s[n:]
+			continue
+		}
+		if m := autolinkRe.FindStringSubmatch(s); m != nil {
+			if safeURL(m[1]) {
+				f.text(m[0])
+			} else {
+				// Escaped, the link is left as text.
+				f.removed++
+				f.text("&lt;" + m[0][1:])
+			}
+			s = s[len(m[0]):]
+			continue
+		}
+		s = s[f.tag(s):]
+	}
+}
+
+// tag writes the html tag or comment s starts with if the policy allows it,
+// and returns its length.
+func (f *markdownFilter) tag(s string) int {
+	z := html.NewTokenizer(strings.NewReader(s))
+	tt := z.Next()
+	n := len(z.Raw())
+	tok := z.Token()
+	switch tt {
+	case html.StartTagToken, html.SelfClosingTagToken:
+		if f.dropped > 0 || dropContent[tok.Data] {
+			if f.dropped == 0 {
+				f.removed++
+			}
+			if tt == html.StartTagToken && dropContent[tok.Data] {
+				f.dropped++
+			}
+			return n
+		}
+		allowed, ok := f.p[tok.Data]
+		if !ok {
+			f.removed++
+			return n
+		}
+		var attrs []html.Attribute
+		for _, a := range tok.Attr {
+			if a.Namespace == "" && (allowed[a.Key] || globalAttrs[a.Key]) && (!urlAttrs[a.Key] || safeURL(a.Val)) {
+				attrs = append(attrs, a)
+			} else {
+				f.removed++
+			}
+		}
+		if tok.Data == "a" {
+			attrs = append(attrs, html.Attribute{Key: "rel", Val: "nofollow"})
+		}
+		tok.Attr = attrs
+		f.text(tok.String())
+	case html.EndTagToken:
+		if dropContent[tok.Data] && f.dropped > 0 {
+			f.dropped--
+		} else if _, ok := f.p[tok.Data]; ok {
+			f.text(tok.String())
+		}
+	case html.CommentToken:
+		if strings.TrimSpace(tok.Data) == "more" {
+			f.text(s[:n])
+		} else if f.dropped == 0 {
+			f.removed++
+		}
+	case html.DoctypeToken:
+		if f.dropped == 0 {
+			f.removed++
+		}
+	default:
+		// A lone "<" is text, and so is a tag left open at the end of the
+		// part once escaped.
+		if len(s) > 1 && (unicode.IsLetter(rune(s[1])) || strings.ContainsRune("/!?", rune(s[1]))) {
+			f.text("&lt;")
+		} else {
+			f.text("<")
+		}
+		return 1
+	}
+	return n
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeMarkdown(t *testing.T) {
+	p, err := newPolicy(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, body, want string
+		removed          int
+	}{
+		{
+			"plain markdown",
+			"## What's Changed\n\n* a < b, 1 <3 and `x<y>`\n",
+			"## What's Changed\n\n* a < b, 1 <3 and `x<y>`\n",
+			0,
+		},
+		{
+			"script over several lines",
+			"Hi <script>\nalert(\"<b>\")\n</script>there",
+			"Hi there",
+			1,
+		},
+		{
+			"attributes",
+			`<img src="javascript:x" onerror="y" alt="Zones"> <a href="https://example.com/">docs</a>`,
+			`<img alt="Zones"> <a href="https://example.com/" rel="nofollow">docs</a>`,
+			2,
+		},
+		{
+			"unknown elements are unwrapped",
+			"<details><summary>More</summary>\n\n<marquee>- x</marquee>\n</details>",
+			"<details><summary>More</summary>\n\n- x\n</details>",
+			1,
+		},
+		{
+			"code",
+			"Use `<iframe>`:\n\n```html\n<iframe src=\"x\"></iframe>\n```\n",
+			"Use `<iframe>`:\n\n```html\n<iframe src=\"x\"></iframe>\n```\n",
+			0,
+		},
+		{
+			"autolinks",
+			"<https://example.com/> <alice@example.com> <javascript:alert(1)>",
+			"<https://example.com/> <alice@example.com> &lt;javascript:alert(1)>",
+			1,
+		},
+		{
+			"comments",
+			"Summary\n<!--more-->\nRest <!-- note -->",
+			"Summary\n<!--more-->\nRest ",
+			1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, removed := p.sanitizeMarkdown(tt.body)
+			if got != tt.want || removed != tt.removed {
+				t.Errorf("sanitizeMarkdown(%q) = %q, %d, want %q, %d", tt.body, got, removed, tt.want, tt.removed)
+			}
+		})
+	}
+}
+
+func TestSanitizeJSON(t *testing.T) {
+	feed := []byte(`[{"id": 1, "html_url": "https://github.com/owner/repo/releases/tag/v1.2.0", "tag_name": "v1.2.0", "body": "Fixed <img src=x onerror=alert(1)> it", "published_at": "2023-04-02T01:30:00Z", "author": {"login": "alice"}}]`)
+	post, err := os.ReadFile(filepath.Join(convert(t, feed, "-sanitize"), "v1.2.0.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(post), "onerror") || !strings.Contains(string(post), `Fixed <img src="x"> it`) {
+		t.Errorf("the markdown body was not sanitized:\n%s", post)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// scrubReplacement replaces text removed by -scrub.
+const scrubReplacement = "[redacted]"
+
+// defaultScrubPatterns match email addresses and hosts, with any URL around
+// them, under domains that are only used on private networks.
+var defaultScrubPatterns = []string{
+	`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+	`(?:https?://)?[A-Za-z0-9.-]+\.(?:internal|local|localdomain|corp|lan|intranet|home\.arpa)\b(?::\d+)?(?:/[^\s"'<>)]*)?`,
+}
+
+// compileScrub compiles the default -scrub patterns followed by patterns.
+func compileScrub(patterns []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, p := range append(append([]string(nil), defaultScrubPatterns...), patterns...) {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -scrub-pattern %q: %s", p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// scrub redacts every match of res in s, reporting how many were replaced.
+func scrub(s string, res []*regexp.Regexp) (string, int) {
+	n := 0
+	for _, re := range res {
+		s = re.ReplaceAllStringFunc(s, func(string) string {
+			n++
+			return scrubReplacement
+		})
+	}
+	return s, n
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// postMeta is the structured metadata of a post, written beside it as
+// <slug>.json with -sidecar-json for scripts on the post page.
+type postMeta struct {
+	ID         string     `json:"id"`
+	Title      string     `json:"title"`
+	Date       time.Time  `json:"date"`
+	Version    string     `json:"version,omitempty"`
+	Repo       string     `json:"repo,omitempty"`
+	Prerelease bool       `json:"prerelease,omitempty"`
+	Author     metaAuthor `json:"author"`
+	Links      metaLinks  `json:"links"`
+	Tags       []string   `json:"tags,omitempty"`
+}
+
+type metaAuthor struct {
+	Name   string `json:"name,omitempty"`
+	URI    string `json:"uri,omitempty"`
+	Avatar string `json:"avatar,omitempty"`
+}
+
+type metaLinks struct {
+	Release       string   `json:"release,omitempty"`
+	Compare       string   `json:"compare,omitempty"`
+	FullChangelog string   `json:"fullChangelog,omitempty"`
+	Assets        []string `json:"assets,omitempty"`
+}
+
+// newPostMeta returns the metadata of e, as written to its post.
+func newPostMeta(e Entry) postMeta {
+	m := postMeta{
+		ID:         e.ID,
+		Title:      e.Title,
+		Date:       time.Time(e.Date),
+		Version:    e.Version,
+		Repo:       e.QualifiedRepo(),
+		Prerelease: e.Prerelease,
+		Author:     metaAuthor{Name: e.Author.Name, URI: e.Author.Uri, Avatar: e.Author.Avatar},
+		Links: metaLinks{
+			Release:       e.Links.Alternate().Href,
+			Compare:       e.CompareURL,
+			FullChangelog: e.FullChangelog,
+		},
+		Tags: e.Tags,
+	}
+	for _, a := range e.Assets {
+		m.Links.Assets = append(m.Links.Assets, a.URL)
+	}
+	return m
+}
+
+// sidecarJSON renders the -sidecar-json file of e.
+func sidecarJSON(e Entry) ([]byte, error) {
+	b, err := json.MarshalIndent(newPostMeta(e), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+)
+
+// singleDocData is passed to the -single-doc template.
+type singleDocData struct {
+	Title   string
+	Updated Date
+	// Posts hold markdown content, with headings demoted below the
+	// release sections.
+	Posts []Entry
+}
+
+var singleDocTempl = `---
+title: "{{ yaml .Title }}"
+{{- if not .Updated.IsZero }}
+date: {{ .Updated }}
+{{- end }}
+---
+{{ range .Posts }}
+## {{ .Title }}
+
+_{{ humandate .Date }}_
+
+{{ .Content }}
+{{ end }}`
+
+var singleDocT = template.Must(template.New("single-doc").Funcs(funcMap).Parse(singleDocTempl))
+
+// renderSingleDoc renders the -single-doc document holding every post as a
+// section, in the given order.
+func renderSingleDoc(exp Export, posts []Entry) ([]byte, error) {
+	title := exp.Title
+	if title == "" {
+		title = "Releases"
+	}
+
+	var buf bytes.Buffer
+	if err := singleDocT.Execute(&buf, singleDocData{
+		Title:   title,
+		Updated: exp.Updated,
+		Posts:   posts,
+	}); err != nil {
+		return nil, err
+	}
+	return endPost(buf.Bytes()), nil
+}
+
+// demoteHeadings moves the ATX headings of a markdown body one level down,
+// so that they nest under the section of their release. Fenced code blocks
+// are left alone.
+func demoteHeadings(markdown string) string {
+	lines := strings.Split(markdown, "\n")
+	fenced := false
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+			continue
+		}
+		if !fenced && strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, "######") {
+			lines[i] = "#" + trimmed
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
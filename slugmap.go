@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// loadSlugMap reads a -slug-map file of "key = slug" lines, where key is an
+// entry ID or title. Blank lines and lines starting with # are ignored.
+func loadSlugMap(filename string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	slugs := make(map[string]string)
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		j := strings.LastIndex(line, "=")
+		if j < 0 {
+			return nil, fmt.Errorf("%s line %d: expected \"key = slug\", got %q", filename, i+1, line)
+		}
+		key, slug := strings.TrimSpace(line[:j]), strings.TrimSpace(line[j+1:])
+		if key == "" || slug == "" || unicodeSanitize(slug) != slug {
+			return nil, fmt.Errorf("%s line %d: invalid slug %q, use letters, digits, '.', '_' and '-'", filename, i+1, slug)
+		}
+		slugs[key] = slug
+	}
+	return slugs, nil
+}
+
+// mappedSlug returns the slug pinned for e by ID, or else by title.
+func mappedSlug(slugs map[string]string, e Entry) (string, bool) {
+	if slug, ok := slugs[e.ID]; ok {
+		return slug, true
+	}
+	slug, ok := slugs[e.Title]
+	return slug, ok
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+)
+
+// wrapBody returns the content of e between the -prepend-file and
+// -append-file snippets, each executed as a template with the entry as
+// data. Either template may be nil.
+func wrapBody(before, after *template.Template, e Entry) (string, error) {
+	head, err := renderSnippet(before, e)
+	if err != nil {
+		return "", err
+	}
+	tail, err := renderSnippet(after, e)
+	if err != nil {
+		return "", err
+	}
+
+	var parts []string
+	for _, part := range []string{head, strings.TrimSpace(e.Content), tail} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// renderSnippet executes tmpl for e, trimming surrounding white space. A nil
+// tmpl renders nothing.
+func renderSnippet(tmpl *template.Template, e Entry) (string, error) {
+	if tmpl == nil {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, e); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
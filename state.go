@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// state is persisted between runs in the -state file. It records the files
+// written for each entry ID, so later runs can tell which posts came from
+// releases that have since disappeared from the feed.
+type state struct {
+	Posts map[string][]string `json:"posts"`
+	// Feeds holds the cache validators of each fetched feed, by repo.
+	Feeds map[string]validators `json:"feeds,omitempty"`
+}
+
+// validators are the HTTP cache validators of a fetched feed, sent back to
+// fetch it only if it changed.
+type validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	// Settings is the settingsDigest of the run that wrote the posts of
+	// the feed. Posts written with other settings are not up to date even
+	// if the feed did not change.
+	Settings string `json:"settings,omitempty"`
+}
+
+// settingsDigest returns a digest of everything besides the feed that the
+// posts in dir depend on: the flags in fs, the files they name, and the
+// version of releasetoblog.
+func settingsDigest(fs *flag.FlagSet, o *options, dir string) (string, error) {
+	h := sha256.New()
+	fmt.Fprintln(h, buildVersion())
+	fmt.Fprintln(h, dir)
+	if err := printConfig(h, fs); err != nil {
+		return "", err
+	}
+
+	files := []string{o.template, o.extraFile, o.slugMap, o.prependFile, o.appendFile}
+	for _, spec := range o.templateRules {
+		files = append(files, spec[strings.LastIndex(spec, "=")+1:])
+	}
+	for _, spec := range o.outputs {
+		if i := strings.Index(spec, ":"); i > 0 {
+			if _, ok := formats[spec[:i]]; !ok {
+				files = append(files, spec[:i])
+			}
+		}
+	}
+	for _, name := range files {
+		if name == "" {
+			continue
+		}
+		b, err := ioutil.ReadFile(name)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s %d\n", name, len(b))
+		h.Write(b)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// loadState reads the state file, returning an empty state if it does not
+// exist yet.
+func loadState(filename string) (*state, error) {
+	s := &state{Posts: make(map[string][]string)}
+
+	b, err := destination.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+	if s.Posts == nil {
+		s.Posts = make(map[string][]string)
+	}
+	return s, nil
+}
+
+func (s *state) save(filename string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = writeFile(filename, append(b, '\n'), true)
+	return err
+}
+
+// record adds a file written for the entry with the given ID.
+func (s *state) record(id, filename string) {
+	for _, f := range s.Posts[id] {
+		if f == filename {
+			return
+		}
+	}
+	s.Posts[id] = append(s.Posts[id], filename)
+}
+
+// missing returns the IDs in the state that are not in ids, sorted.
+func (s *state) missing(ids map[string]bool) []string {
+	var found []string
+	for id := range s.Posts {
+		if !ids[id] {
+			found = append(found, id)
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+// pruneFlag is the -prune mode. A bare -prune deletes, -prune=soft marks the
+// posts as drafts instead.
+type pruneFlag string
+
+func (f *pruneFlag) String() string   { return string(*f) }
+func (f *pruneFlag) IsBoolFlag() bool { return true }
+
+func (f *pruneFlag) Set(s string) error {
+	switch s {
+	case "true", "hard":
+		*f = "hard"
+	case "soft":
+		*f = "soft"
+	case "false":
+		*f = ""
+	default:
+		return errors.New("use -prune or -prune=soft")
+	}
+	return nil
+}
+
+// markDraft sets draft: true in the frontmatter of a post, reporting whether
+// the file changed.
+func markDraft(filename string) (bool, error) {
+	b, err := destination.ReadFile(filename)
+	if err != nil {
+		return false, err
+	}
+
+	lines := bytes.SplitAfter(b, []byte("\n"))
+	if len(lines) == 0 || string(bytes.TrimSpace(lines[0])) != "---" {
+		return false, nil
+	}
+
+	key := mappedKey("draft")
+	for i := 1; i < len(lines); i++ {
+		line := string(bytes.TrimSpace(lines[i]))
+		if line == "---" {
+			// No draft key in the frontmatter, add one before the end.
+			lines = append(lines[:i], append([][]byte{[]byte(key + ": true\n")}, lines[i:]...)...)
+			break
+		}
+		if line == key+": true" {
+			return false, nil
+		}
+		if bytes.HasPrefix(lines[i], []byte(key+":")) {
+			lines[i] = []byte(key + ": true\n")
+			break
+		}
+	}
+
+	return writeFile(filename, bytes.Join(lines, nil), true)
+}
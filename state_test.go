@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConditionalFetch(t *testing.T) {
+	tests := []struct {
+		args                  []string
+		conditional, filtered bool
+	}{
+		{[]string{"-state", "s.json"}, true, false},
+		{nil, false, false},
+		{[]string{"-state", "s.json", "-force"}, false, false},
+		{[]string{"-state", "s.json", "-check"}, false, false},
+		{[]string{"-state", "s.json", "-list"}, false, false},
+		{[]string{"-state", "s.json", "-stdout"}, false, false},
+		{[]string{"-state", "s.json", "-diff"}, false, false},
+		{[]string{"-state", "s.json", "-archive", "a.zip"}, false, false},
+		{[]string{"-state", "s.json", "-max-age", "30d"}, false, false},
+		{[]string{"-state", "s.json", "-limit", "1"}, true, true},
+		{[]string{"-state", "s.json", "-match", "^v1"}, true, true},
+		{[]string{"-state", "s.json", "-exclude-authors", "bot"}, true, true},
+	}
+	for _, tt := range tests {
+		o := &options{}
+		if err := parseArgs(o, tt.args...); err != nil {
+			t.Fatal(err)
+		}
+		if got := o.conditionalFetch(); got != tt.conditional {
+			t.Errorf("%q: conditionalFetch() = %v, want %v", tt.args, got, tt.conditional)
+		}
+		if got := o.filtered(); got != tt.filtered {
+			t.Errorf("%q: filtered() = %v, want %v", tt.args, got, tt.filtered)
+		}
+	}
+}
+
+func TestSettingsDigest(t *testing.T) {
+	tmpl := filepath.Join(t.TempDir(), "post.tmpl")
+	if err := os.WriteFile(tmpl, []byte("{{ .Title }}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	digest := func(dir string, args ...string) string {
+		t.Helper()
+		o := &options{}
+		fs := flag.NewFlagSet("releasetoblog", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		o.register(fs)
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		d, err := settingsDigest(fs, o, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	base := digest("posts", "-template", tmpl)
+	if d := digest("posts", "-template", tmpl); d != base {
+		t.Error("the digest of the same settings changed")
+	}
+	if d := digest("other", "-template", tmpl); d == base {
+		t.Error("the digest does not depend on the target directory")
+	}
+	if d := digest("posts", "-template", tmpl, "-convert"); d == base {
+		t.Error("the digest does not depend on the flags")
+	}
+	if err := os.WriteFile(tmpl, []byte("{{ .Title }}!\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if d := digest("posts", "-template", tmpl); d == base {
+		t.Error("the digest does not depend on the template file")
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// selector is a simple CSS selector: an optional tag name followed by any
+// number of #id, .class and [attr], [attr=value], [attr^=value] or
+// [attr*=value] conditions, e.g. img[src*=shields.io] or a.badge.
+type selector struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   []attrCond
+}
+
+type attrCond struct {
+	name, op, value string
+}
+
+// parseSelectors parses a comma separated list of selectors.
+func parseSelectors(s string) ([]selector, error) {
+	var sels []selector
+	for _, part := range strings.Split(s, ",") {
+		sel, err := parseSelector(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+	}
+	return sels, nil
+}
+
+func parseSelector(s string) (selector, error) {
+	var sel selector
+	if s == "" {
+		return sel, fmt.Errorf("empty selector")
+	}
+
+	name := func(i int) int {
+		j := i
+		for j < len(s) && isNameByte(s[j]) {
+			j++
+		}
+		return j
+	}
+
+	i := name(0)
+	sel.tag = strings.ToLower(s[:i])
+	if sel.tag == "" && i < len(s) && s[i] == '*' {
+		i++
+	}
+
+	for i < len(s) {
+		switch s[i] {
+		case '#', '.':
+			j := name(i + 1)
+			if j == i+1 {
+				return sel, fmt.Errorf("invalid selector %q: missing name after %q", s, s[i])
+			}
+			if s[i] == '#' {
+				sel.id = s[i+1 : j]
+			} else {
+				sel.classes = append(sel.classes, s[i+1:j])
+			}
+			i = j
+		case '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return sel, fmt.Errorf("invalid selector %q: unclosed [", s)
+			}
+			cond, err := parseAttrCond(s[i+1 : i+end])
+			if err != nil {
+				return sel, fmt.Errorf("invalid selector %q: %s", s, err)
+			}
+			sel.attrs = append(sel.attrs, cond)
+			i += end + 1
+		default:
+			return sel, fmt.Errorf("invalid selector %q: unexpected %q", s, s[i])
+		}
+	}
+	return sel, nil
+}
+
+func parseAttrCond(s string) (attrCond, error) {
+	for _, op := range []string{"^=", "*=", "="} {
+		if i := strings.Index(s, op); i > 0 {
+			value := strings.Trim(s[i+len(op):], `"'`)
+			return attrCond{name: strings.ToLower(strings.TrimSpace(s[:i])), op: op, value: value}, nil
+		}
+	}
+	if s == "" {
+		return attrCond{}, fmt.Errorf("empty attribute condition")
+	}
+	return attrCond{name: strings.ToLower(strings.TrimSpace(s))}, nil
+}
+
+func isNameByte(c byte) bool {
+	return c == '-' || c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func (sel selector) matches(n *html.Node) bool {
+	if n.Type != html.ElementNode || (sel.tag != "" && sel.tag != n.Data) {
+		return false
+	}
+	if sel.id != "" && attr(n, "id") != sel.id {
+		return false
+	}
+	classes := strings.Fields(attr(n, "class"))
+	for _, c := range sel.classes {
+		if !contains(classes, c) {
+			return false
+		}
+	}
+	for _, cond := range sel.attrs {
+		v, ok := lookupAttr(n, cond.name)
+		switch {
+		case !ok:
+			return false
+		case cond.op == "=" && v != cond.value,
+			cond.op == "^=" && !strings.HasPrefix(v, cond.value),
+			cond.op == "*=" && !strings.Contains(v, cond.value):
+			return false
+		}
+	}
+	return true
+}
+
+func attr(n *html.Node, name string) string {
+	v, _ := lookupAttr(n, name)
+	return v
+}
+
+func lookupAttr(n *html.Node, name string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// parseBody parses an html release body as the children of a <body>.
+func parseBody(body string) ([]*html.Node, error) {
+	return html.ParseFragment(strings.NewReader(body), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+}
+
+// renderNodes serializes nodes back to html. html.Render escapes the quotes
+// in text as &#39; and &#34;, which html2md would leave in the markdown, so
+// they are kept as they are: only attribute values need them escaped.
+func renderNodes(nodes []*html.Node) (string, error) {
+	var buf bytes.Buffer
+	for _, n := range nodes {
+		markQuotes(n)
+		if err := html.Render(&buf, n); err != nil {
+			return "", err
+		}
+	}
+	out := strings.ReplaceAll(buf.String(), "&#39;", "'")
+	return strings.ReplaceAll(out, "\x00", `"`), nil
+}
+
+// markQuotes replaces the double quotes in the text under n with NUL, which
+// the html parser never leaves in text and Render writes as is, for
+// renderNodes to put them back. Single quotes need no marking, as Render
+// double quotes attribute values.
+func markQuotes(n *html.Node) {
+	if n.Type == html.TextNode {
+		n.Data = strings.ReplaceAll(n.Data, `"`, "\x00")
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		markQuotes(c)
+	}
+}
+
+// stripElements removes the elements matching any of sels from an html
+// release body.
+func stripElements(body string, sels []selector) (string, error) {
+	nodes, err := parseBody(body)
+	if err != nil {
+		return "", err
+	}
+
+	var kept []*html.Node
+	for _, n := range nodes {
+		if !matchesAny(n, sels) {
+			removeMatching(n, sels)
+			kept = append(kept, n)
+		}
+	}
+	return renderNodes(kept)
+}
+
+func removeMatching(n *html.Node, sels []selector) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if matchesAny(c, sels) {
+			n.RemoveChild(c)
+		} else {
+			removeMatching(c, sels)
+		}
+		c = next
+	}
+}
+
+func matchesAny(n *html.Node, sels []selector) bool {
+	for _, sel := range sels {
+		if sel.matches(n) {
+			return true
+		}
+	}
+	return false
+}
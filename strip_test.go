@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// quotesFeed is a release whose notes have quotes in text and attributes,
+// and a badge for -strip-selector to remove.
+const quotesFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <id>tag:github.com,2008:https://github.com/owner/repo/releases</id>
+  <title>Release notes from repo</title>
+  <updated>2023-04-02T01:30:00Z</updated>
+  <entry>
+    <id>tag:github.com,2008:Repository/1/v1.2.0</id>
+    <updated>2023-04-02T01:30:00Z</updated>
+    <link rel="alternate" type="text/html" href="https://github.com/owner/repo/releases/tag/v1.2.0"/>
+    <title>v1.2.0</title>
+    <content type="html">%s</content>
+    <author><name>alice</name></author>
+  </entry>
+</feed>
+`
+
+func TestStripKeepsQuotes(t *testing.T) {
+	body := `&lt;p&gt;&lt;img src="https://img.shields.io/badge/build-passing-green"&gt;&lt;/p&gt;
+&lt;h2&gt;What's Changed&lt;/h2&gt;
+&lt;p&gt;It's "fast" now, see &lt;a href="https://example.com/" title="the &amp;quot;docs&amp;quot;"&gt;Bob's notes&lt;/a&gt;.&lt;/p&gt;`
+	dir := convert(t, []byte(fmt.Sprintf(quotesFeed, body)), "-convert", "-strip-selector", "img[src*=shields.io]")
+	post, err := os.ReadFile(filepath.Join(dir, "v1.2.0.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"## What's Changed", `It's "fast" now`, "[Bob's notes](https://example.com/"} {
+		if !strings.Contains(string(post), want) {
+			t.Errorf("post does not contain %q:\n%s", want, post)
+		}
+	}
+	for _, unwanted := range []string{"&#39;", "&#34;", "shields.io"} {
+		if strings.Contains(string(post), unwanted) {
+			t.Errorf("post contains %q:\n%s", unwanted, post)
+		}
+	}
+}
+
+func TestRenderNodesQuotes(t *testing.T) {
+	tests := []struct {
+		body, want string
+	}{
+		{`<p>It's "fast"</p>`, `<p>It's "fast"</p>`},
+		{`<p>It&#39;s &quot;fast&quot;</p>`, `<p>It's "fast"</p>`},
+		// Attribute values are double quoted, so their double quotes
+		// stay escaped.
+		{`<a title='say "hi"' href="x">it's</a>`, `<a title="say &#34;hi&#34;" href="x">it's</a>`},
+		{`<p>a &lt;b&gt; &amp; c</p>`, `<p>a &lt;b&gt; &amp; c</p>`},
+		{`<script>if (a > "b") {}</script>`, `<script>if (a > "b") {}</script>`},
+	}
+	for _, tt := range tests {
+		nodes, err := parseBody(tt.body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := renderNodes(nodes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("renderNodes(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
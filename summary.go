@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"golang.org/x/net/html"
+)
+
+// summaryPost is a release listed in the -summary-file.
+type summaryPost struct {
+	Entry
+	// Summary is a one line description taken from the release notes.
+	Summary string
+}
+
+// summaryData is passed to the summary template.
+type summaryData struct {
+	Title string
+	Posts []summaryPost
+}
+
+// summaryTempl follows the llms.txt format: a title, a quoted abstract and
+// a list of links with notes.
+var summaryTempl = `# {{ .Title }}
+
+> One line summaries of {{ len .Posts }} releases, newest first.
+
+## Releases
+{{ range .Posts }}
+- [{{ .Title }}]({{ .Links.Alternate.Href }}): {{ ymd .Date }}
+{{- with .QualifiedRepo }}, {{ . }}{{ end }}
+{{- with .Version }}, version {{ . }}{{ end }}
+{{- with .Summary }}. {{ . }}{{ end }}
+{{- end }}
+`
+
+var summaryT = template.Must(template.New("summary").Funcs(funcMap).Parse(summaryTempl))
+
+// summaryLen is the maximum length of a release's summary line.
+const summaryLen = 160
+
+// summaryLine returns the first paragraph or list item of an html or
+// markdown body as a single line, cut after its first sentence.
+func summaryLine(body string, markdown bool) string {
+	var text string
+	if markdown {
+		text = markdownSummary(body)
+	} else {
+		text = htmlSummary(body)
+	}
+	if i := strings.Index(text, ". "); i >= 0 {
+		text = text[:i]
+	}
+	return truncate(summaryLen, strings.TrimSuffix(text, "."))
+}
+
+// markdownImageLineRe matches a whole markdown image, which holds no text
+// to summarize.
+var markdownImageLineRe = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+
+// markdownSummary returns the first line of text of a markdown body that is
+// not a heading, without its list or quote marker.
+func markdownSummary(body string) string {
+	for _, line := range markdownLines(body) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.TrimSpace(markdownImageLineRe.ReplaceAllString(line, "")) == "" {
+			continue
+		}
+		if m := markdownItemRe.FindStringSubmatch(line); m != nil {
+			line = m[1]
+		}
+		if text := strings.Join(strings.Fields(strings.TrimLeft(line, "> ")), " "); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// htmlSummary returns the text of the first paragraph or list item of an
+// html body.
+func htmlSummary(body string) string {
+	nodes, err := parseBody(body)
+	if err != nil {
+		return ""
+	}
+
+	var text string
+	var walk func(n *html.Node) bool
+	walk = func(n *html.Node) bool {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				return false
+			case "p", "li", "blockquote", "pre":
+				text = strings.Join(strings.Fields(textContent(n)), " ")
+				return text != ""
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if walk(c) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, n := range nodes {
+		if walk(n) {
+			break
+		}
+	}
+	return text
+}
+
+// renderSummary renders the -summary-file listing posts, newest first.
+func renderSummary(title string, posts []summaryPost) ([]byte, error) {
+	sorted := append([]summaryPost(nil), posts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return dateLess(sorted[i].Date, sorted[j].Date, "desc")
+	})
+	if title == "" {
+		title = "Releases"
+	}
+
+	var buf bytes.Buffer
+	if err := summaryT.Execute(&buf, summaryData{Title: title, Posts: sorted}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// taskMarkers replaces the checkboxes that start the items of Github task
+// lists with the [x] and [ ] markers of GFM task lists, which html2md would
+// otherwise drop along with the input elements.
+func taskMarkers(body string) (string, error) {
+	if !strings.Contains(body, "checkbox") {
+		return body, nil
+	}
+	nodes, err := parseBody(body)
+	if err != nil {
+		return "", err
+	}
+
+	n := 0
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		for c := node.FirstChild; c != nil; {
+			next := c.NextSibling
+			if isTaskCheckbox(c) {
+				marker := "[ ] "
+				if hasAttr(c, "checked") {
+					marker = "[x] "
+				}
+				if next != nil && next.Type == html.TextNode {
+					next.Data = strings.TrimLeft(next.Data, " \t")
+				}
+				node.InsertBefore(&html.Node{Type: html.TextNode, Data: marker}, c)
+				node.RemoveChild(c)
+				n++
+			} else {
+				walk(c)
+			}
+			c = next
+		}
+	}
+	for _, node := range nodes {
+		walk(node)
+	}
+	if n == 0 {
+		return body, nil
+	}
+	return renderNodes(nodes)
+}
+
+// isTaskCheckbox reports whether n is a checkbox opening a list item, as
+// Github renders "- [x] item", directly or in the item's first paragraph.
+func isTaskCheckbox(n *html.Node) bool {
+	if n.DataAtom != atom.Input || attr(n, "type") != "checkbox" || !leading(n) {
+		return false
+	}
+	p := n.Parent
+	if p != nil && p.DataAtom == atom.P && leading(p) {
+		p = p.Parent
+	}
+	return p != nil && p.DataAtom == atom.Li
+}
+
+// leading reports whether only blank text comes before n in its parent.
+func leading(n *html.Node) bool {
+	for c := n.PrevSibling; c != nil; c = c.PrevSibling {
+		if c.Type != html.TextNode || strings.TrimSpace(c.Data) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// hasAttr reports whether n has the attribute key, whatever its value.
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// taskItemRe matches the bullets html2md writes for list items, capturing
+// their indentation and any task marker added by taskMarkers.
+var taskItemRe = regexp.MustCompile(`^( *)\*   (\[[ x]\] )?`)
+
+// taskListMarkdown rewrites the lists of converted markdown that hold task
+// items to use "- " bullets, as Github writes "- [x] item", instead of the
+// "*   " of html2md. Every item of such a list is rewritten, so that the
+// list is not split in two by a change of bullet.
+func taskListMarkdown(md string) string {
+	lines := strings.Split(md, "\n")
+	listed := make([]bool, len(lines))
+	for i := 0; i < len(lines); i++ {
+		m := taskItemRe.FindStringSubmatch(lines[i])
+		if m == nil || listed[i] {
+			continue
+		}
+
+		// The list goes on over its bullets at the same indentation, the
+		// more indented lines under them, and blank lines between them.
+		indent := m[1]
+		items := []int{i}
+		task := m[2] != ""
+		for j := i + 1; j < len(lines); j++ {
+			line := lines[j]
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if m := taskItemRe.FindStringSubmatch(line); m != nil && m[1] == indent {
+				items = append(items, j)
+				task = task || m[2] != ""
+				continue
+			}
+			if !strings.HasPrefix(line, indent+" ") {
+				break
+			}
+		}
+
+		// Nested lists are left to be found on their own as the scan goes
+		// on, while the other items of this one are skipped.
+		for _, j := range items {
+			listed[j] = true
+			if task {
+				lines[j] = indent + "- " + lines[j][len(indent)+4:]
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
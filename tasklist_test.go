@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTaskListRoundTrip(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "tasklist.atom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	post, err := os.ReadFile(filepath.Join(convert(t, b, "-convert"), "v1.2.0.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"\n- [x] Back up the database, it's \"cheap\"\n",
+		"\n- [ ] Run `migrate`\n",
+		// Re-rendering the html for the markers keeps quotes as they are.
+		"\n## What's Changed\n",
+		// Lists without checkboxes keep the bullets of html2md.
+		"\n*   Faster imports\n",
+	} {
+		if !strings.Contains(string(post), want) {
+			t.Errorf("post does not contain %q:\n%s", strings.TrimSpace(want), post)
+		}
+	}
+}
+
+func TestTaskListMarkdown(t *testing.T) {
+	tests := []struct {
+		name, md, want string
+	}{
+		{
+			"plain list",
+			"*   one\n*   two\n",
+			"*   one\n*   two\n",
+		},
+		{
+			"mixed list",
+			"*   [x] done\n*   note\n*   [ ] todo\n\nAfter.\n",
+			"- [x] done\n- note\n- [ ] todo\n\nAfter.\n",
+		},
+		{
+			"nested",
+			"*   parent\n    *   [x] child\n    *   [ ] other\n*   sibling\n",
+			"*   parent\n    - [x] child\n    - [ ] other\n*   sibling\n",
+		},
+		{
+			"separate lists",
+			"*   [x] done\n\n## Changes\n\n*   faster\n",
+			"- [x] done\n\n## Changes\n\n*   faster\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := taskListMarkdown(tt.md); got != tt.want {
+				t.Errorf("taskListMarkdown(%q) = %q, want %q", tt.md, got, tt.want)
+			}
+		})
+	}
+}
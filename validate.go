@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// validateFrontmatter parses the frontmatter of a rendered post, YAML
+// between --- lines or TOML between +++ lines, returning it along with any
+// parse error. Posts without frontmatter are valid.
+func validateFrontmatter(post []byte) (string, error) {
+	post = bytes.ReplaceAll(post, []byte("\r\n"), []byte("\n"))
+	for _, delim := range []string{"---", "+++"} {
+		open := []byte(delim + "\n")
+		if !bytes.HasPrefix(post, open) {
+			continue
+		}
+		rest := post[len(open):]
+		var fm []byte
+		if !bytes.HasPrefix(rest, open) {
+			i := bytes.Index(rest, []byte("\n"+delim+"\n"))
+			if i < 0 {
+				if !bytes.HasSuffix(rest, []byte("\n"+delim)) {
+					return string(rest), fmt.Errorf("frontmatter is missing its closing %s", delim)
+				}
+				i = len(rest) - len(delim) - 1
+			}
+			fm = rest[:i+1]
+		}
+
+		var v map[string]interface{}
+		if delim == "+++" {
+			return string(fm), toml.Unmarshal(fm, &v)
+		}
+		return string(fm), yaml.Unmarshal(fm, &v)
+	}
+	return "", nil
+}
+
+// validateEntry renders e with the output's template and parses the
+// frontmatter of the result.
+func validateEntry(out output, e Entry) (string, error) {
+	b, err := renderEntry(out, e)
+	if err != nil {
+		return "", err
+	}
+	return validateFrontmatter(b)
+}